@@ -0,0 +1,365 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Curator dashboard served by "ask-and-learn serve" under /admin when the
+// configuration names the variable holding its password (see config.go).
+// Browsers authenticate with HTTP basic authentication.  The page shows the
+// tree as collapsible outline, the review queue (texts flagged by moderation
+// and questions players found confusing) and charts of play statistics.
+//
+//	GET  /admin         dashboard page
+//	GET  /admin/tree    tree (JSON)
+//	GET  /admin/review  review queue (JSON)
+//	POST /admin/review  apply action approve, delete or edit (with text) to node id
+//	GET  /admin/stats   statistics (JSON)
+//	POST /admin/undo    undo last change made from the dashboard (see undo.go)
+//	POST /admin/redo    redo last undone change
+//
+// Changes require the X-Admin header, which cross-site forms can not send.
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Default name of curator
+const adminUser = "admin"
+
+// Node as shown by the dashboard
+type adminNode struct {
+	ID      string
+	Text    string
+	Animal  bool       `json:",omitempty"`
+	Path    string     // answers leading to node, e.g. yny
+	Count   int        // times answered or chosen
+	Flagged string     `json:",omitempty"`
+	Shared  bool       `json:",omitempty"`
+	Yes, No *adminNode `json:",omitempty"`
+}
+
+type reviewItem struct {
+	ID, Path, Kind, Text, Reason string
+}
+
+type adminStats struct {
+	Games, Animals, Questions int
+
+	// Number of animals by depth
+	Depths []int
+
+	// Most chosen animals
+	Top []animalEntry
+}
+
+// Password of curator, "" if the dashboard is disabled
+func adminPassword() string {
+	if env := settings().AdminPasswordEnv; env != "" {
+		return os.Getenv(env)
+	}
+	return ""
+}
+
+// Report whether request carries the credentials of the curator
+func isCurator(r *http.Request) bool {
+	password := adminPassword()
+	user := settings().AdminUser
+	if user == "" {
+		user = adminUser
+	}
+	u, p, ok := r.BasicAuth()
+	return ok && password != "" && subtle.ConstantTimeCompare([]byte(u), []byte(user)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(p), []byte(password)) == 1
+}
+
+func (s *server) handleAdmin() {
+	if adminPassword() == "" {
+		if env := settings().AdminPasswordEnv; env != "" {
+			log.Printf("$%s not set: /admin disabled", env)
+		}
+		return
+	}
+	http.HandleFunc("/admin", authenticated(handleAdminPage))
+	http.HandleFunc("/admin/tree", authenticated(s.handleAdminTree))
+	http.HandleFunc("/admin/review", authenticated(s.handleReview))
+	http.HandleFunc("/admin/stats", authenticated(s.handleAdminStats))
+	http.HandleFunc("/admin/undo", authenticated(s.handleUndo(s.history.back)))
+	http.HandleFunc("/admin/redo", authenticated(s.handleUndo(s.history.forward)))
+}
+
+func authenticated(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !isCurator(r) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="ask-and-learn"`)
+			http.Error(w, "authentication required", http.StatusUnauthorized)
+			return
+		}
+		if r.Method != "GET" && r.Header.Get("X-Admin") == "" {
+			http.Error(w, "X-Admin header expected", http.StatusForbidden)
+			return
+		}
+		h(w, r)
+	}
+}
+
+func handleAdminPage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(adminPage))
+}
+
+func toAdminNode(n *node, path string, shared map[*node]bool) *adminNode {
+	a := &adminNode{ID: n.ID, Path: path, Flagged: n.Flagged, Shared: shared[n]}
+	if n.isLeaf() {
+		a.Text, a.Animal, a.Count = n.Animal, true, n.ChosenCount
+		return a
+	}
+	a.Text, a.Count = n.Question, n.YesCount+n.NoCount
+	a.Yes = toAdminNode(n.Yes, path+"y", shared)
+	a.No = toAdminNode(n.No, path+"n", shared)
+	return a
+}
+
+func (s *server) handleAdminTree(w http.ResponseWriter, r *http.Request) {
+	d := s.view()
+	writeJSON(w, toAdminNode(d.Root, "", sharedNodes(d.Root)))
+}
+
+// Nodes awaiting review, flagged ones first
+func (d *database) reviewQueue() []reviewItem {
+	items := []reviewItem{}
+	var confusing []*node
+	visit(d.Root, func(n *node) {
+		kind := "question"
+		if n.isLeaf() {
+			kind = "animal"
+		}
+		switch {
+		case n.Flagged != "":
+			items = append(items, reviewItem{n.ID, pathString(pathTo(d.Root, n)), kind, n.text(), n.Flagged})
+		case n.ConfusingCount > 0:
+			confusing = append(confusing, n)
+		}
+	})
+	sort.SliceStable(confusing, func(i, j int) bool { return confusing[i].ConfusingCount > confusing[j].ConfusingCount })
+	for _, n := range confusing {
+		items = append(items, reviewItem{n.ID, pathString(pathTo(d.Root, n)), "question", n.Question,
+			fmt.Sprintf("confusing for %d player(s)", n.ConfusingCount)})
+	}
+	return items
+}
+
+func (s *server) handleReview(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		writeJSON(w, s.view().reviewQueue())
+	case "POST":
+		s.Lock()
+		defer s.Unlock()
+		index := make(map[string]*node)
+		indexTree(s.db.Root, index)
+		n := index[r.FormValue("id")]
+		if n == nil {
+			http.Error(w, "unknown node", http.StatusNotFound)
+			return
+		}
+		c, err := s.db.review(n, r.FormValue("action"), strings.TrimSpace(r.FormValue("text")))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		s.history.push(c)
+		log.Printf("%s: %s %q", r.RemoteAddr, r.FormValue("action"), n.text())
+		s.save()
+		writeJSON(w, s.db.reviewQueue())
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// Apply review action to node n.  Returns the change for undoing it.
+func (d *database) review(n *node, action, text string) (change, error) {
+	approved := &approval{id: n.ID, flagged: n.Flagged, confusing: n.ConfusingCount}
+	switch action {
+	case "approve":
+		n.Flagged, n.ConfusingCount = "", 0
+		return approved, nil
+	case "delete":
+		if !n.isLeaf() {
+			return nil, fmt.Errorf("only animals can be deleted")
+		}
+		c := newRemoval(d, n)
+		if c == nil {
+			return nil, fmt.Errorf("can not delete last animal")
+		}
+		d.remove(n)
+		return c, nil
+	case "edit":
+		if text == "" {
+			return nil, fmt.Errorf("new text expected")
+		}
+		if v, reason := moderate(text, !n.isLeaf()); v == reject {
+			return nil, fmt.Errorf("%s", reason)
+		}
+		renamed := &renaming{id: n.ID, from: n.text(), to: text}
+		if !n.isLeaf() {
+			d.editQuestion(n, text)
+		} else if other := d.findAnimal(text); other != nil && other != n {
+			return nil, fmt.Errorf("%s already known", other.Animal)
+		} else {
+			d.rename(n, n.Animal, text)
+		}
+		n.Flagged, n.ConfusingCount = "", 0
+		return changes{renamed, approved}, nil
+	default:
+		return nil, fmt.Errorf("unknown action %q", action)
+	}
+}
+
+// Handler undoing or redoing change with f
+func (s *server) handleUndo(f func(d *database) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.Lock()
+		defer s.Unlock()
+		if err := f(s.db); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("%s: %s", r.RemoteAddr, strings.TrimPrefix(r.URL.Path, "/admin/"))
+		s.save()
+		writeJSON(w, s.db.reviewQueue())
+	}
+}
+
+func (s *server) handleAdminStats(w http.ResponseWriter, r *http.Request) {
+	d := s.view()
+	entries := listAnimals(d.Root, 0)
+	st := &adminStats{Animals: len(entries), Questions: len(questionNodes(d.Root)), Depths: []int{}}
+	for _, e := range entries {
+		st.Games += e.ChosenCount
+		for len(st.Depths) <= e.Depth {
+			st.Depths = append(st.Depths, 0)
+		}
+		st.Depths[e.Depth]++
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].ChosenCount > entries[j].ChosenCount })
+	for i := 0; i < len(entries) && i < statsTop && entries[i].ChosenCount > 0; i++ {
+		st.Top = append(st.Top, entries[i])
+	}
+	writeJSON(w, st)
+}
+
+const adminPage = `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>ask-and-learn admin</title>
+<style>
+body { font-family: sans-serif; }
+details { margin-left: 1.5em; }
+.answer { color: gray; }
+.flagged { color: darkred; }
+.shared { font-style: italic; }
+.bar { background: steelblue; color: white; padding: 0 0.3em; margin: 2px 0; white-space: nowrap; }
+</style></head>
+<body>
+<h1>ask-and-learn</h1>
+<p><button onclick="undo('undo')">Undo</button> <button onclick="undo('redo')">Redo</button></p>
+<h2>Review queue</h2>
+<table id="review"></table>
+<h2>Statistics</h2>
+<p id="summary"></p>
+<h3>Most chosen animals</h3>
+<div id="top"></div>
+<h3>Animals by number of questions</h3>
+<div id="depths"></div>
+<h2>Tree</h2>
+<div id="tree"></div>
+<script>
+function $(id) { return document.getElementById(id); }
+function call(method, path, params) {
+	var q = new URLSearchParams(params).toString();
+	return fetch(path + "?" + q, {method: method, headers: {"X-Admin": "1"}}).then(function(r) {
+		if (!r.ok) { return r.text().then(function(t) { throw new Error(t); }); }
+		return r.json();
+	});
+}
+function el(tag, text, cls) { var e = document.createElement(tag); e.textContent = text; if (cls) { e.className = cls; } return e; }
+function label(n, answer) {
+	var s = el("span", "");
+	if (answer) { s.appendChild(el("span", answer + ": ", "answer")); }
+	s.appendChild(el("span", n.Text + " (" + n.Count + ")", n.Flagged ? "flagged" : n.Shared ? "shared" : ""));
+	return s;
+}
+function outline(n, answer) {
+	if (n.Animal) { var d = el("div", ""); d.style.marginLeft = "1.5em"; d.appendChild(label(n, answer)); return d; }
+	var d = el("details", "");
+	var s = el("summary", ""); s.appendChild(label(n, answer)); d.appendChild(s);
+	d.addEventListener("toggle", function() {
+		if (d.open && d.children.length == 1) { d.appendChild(outline(n.Yes, "yes")); d.appendChild(outline(n.No, "no")); }
+	});
+	return d;
+}
+function bars(div, rows) {
+	div.innerHTML = "";
+	var max = Math.max.apply(null, rows.map(function(r) { return r[1]; }).concat([1]));
+	rows.forEach(function(r) { var b = el("div", r[0] + ": " + r[1], "bar"); b.style.width = (100 * r[1] / max) + "%"; div.appendChild(b); });
+}
+function review(item, action) {
+	var params = {id: item.ID, action: action};
+	if (action == "edit") { var t = prompt("New text", item.Text); if (!t) { return; } params.text = t; }
+	call("POST", "/admin/review", params).then(refresh).catch(alert);
+}
+function refresh(items) { showReview(items); call("GET", "/admin/stats", {}).then(showStats); return loadTree(); }
+function undo(what) { call("POST", "/admin/" + what, {}).then(refresh).catch(alert); }
+function button(text, f) { var b = el("button", text); b.onclick = f; return b; }
+function showReview(items) {
+	var t = $("review"); t.innerHTML = "";
+	if (items.length == 0) { t.appendChild(el("tr", "Nothing to review.")); }
+	items.forEach(function(item) {
+		var tr = el("tr", "");
+		[item.Path || "root", item.Kind, item.Text, item.Reason].forEach(function(c) { tr.appendChild(el("td", c)); });
+		var td = el("td", "");
+		td.appendChild(button("Approve", function() { review(item, "approve"); }));
+		td.appendChild(button("Edit", function() { review(item, "edit"); }));
+		if (item.Kind != "question") { td.appendChild(button("Delete", function() { review(item, "delete"); })); }
+		tr.appendChild(td); t.appendChild(tr);
+	});
+}
+function showStats(s) {
+	$("summary").textContent = s.Games + " games, " + s.Animals + " animals, " + s.Questions + " questions";
+	bars($("top"), (s.Top || []).map(function(e) { return [e.Animal, e.ChosenCount]; }));
+	bars($("depths"), s.Depths.map(function(c, i) { return [i, c]; }).filter(function(r) { return r[1] > 0; }));
+}
+function loadTree() { return call("GET", "/admin/tree", {}).then(function(t) { $("tree").innerHTML = ""; $("tree").appendChild(outline(t, "")); }); }
+call("GET", "/admin/review", {}).then(showReview).catch(alert);
+call("GET", "/admin/stats", {}).then(showStats).catch(alert);
+loadTree().catch(alert);
+</script>
+</body></html>
+`
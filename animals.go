@@ -0,0 +1,190 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+func init() {
+	cmd := &command{
+		Name:  "animals",
+		Args:  "database-file",
+		Short: "list known animals",
+		Run:   runAnimals,
+	}
+	animalsSort = cmd.Flag.Bool("sort", false, "sort alphabetically instead of in tree order")
+	animalsCount = cmd.Flag.Bool("count", false, "print number of animals only")
+	animalsJSON = cmd.Flag.Bool("json", false, "print JSON array of animals with depth and times chosen")
+	commands = append(commands, cmd)
+
+	commands = append(commands, &command{
+		Name:  "find",
+		Args:  "database-file name",
+		Short: "print questions leading to animal, or to animals with a similar name",
+		Run:   runFind,
+	})
+}
+
+var (
+	animalsSort  *bool
+	animalsCount *bool
+	animalsJSON  *bool
+)
+
+// Animal as listed by -json
+type animalEntry struct {
+	Animal      string
+	Depth       int
+	ChosenCount int
+}
+
+// Leaves of subtree n, which is at depth, in tree order
+func listAnimals(n *node, depth int) []animalEntry {
+	var found []animalEntry
+	// Animals shared between branches (see dag.go) are listed once at
+	// their first depth.
+	seen := make(map[*node]bool)
+	var walk func(n *node, depth int)
+	walk = func(n *node, depth int) {
+		if seen[n] {
+			return
+		}
+		seen[n] = true
+		if n.isLeaf() {
+			found = append(found, animalEntry{n.Animal, depth, n.ChosenCount})
+			return
+		}
+		walk(n.No, depth+1)
+		walk(n.Yes, depth+1)
+	}
+	walk(n, depth)
+	return found
+}
+
+func runAnimals(cmd *command, args []string) {
+	if len(args) != 1 {
+		cmd.fail("database expected")
+	}
+	d, err := loadDatabase(args[0])
+	if err != nil {
+		log.Panic("can not load db: ", err)
+	}
+	entries := listAnimals(d.Root, 0)
+	if *animalsSort {
+		sort.SliceStable(entries, func(i, j int) bool { return fold(entries[i].Animal) < fold(entries[j].Animal) })
+	}
+
+	switch {
+	case *animalsCount:
+		fmt.Println(len(entries))
+	case *animalsJSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "    ")
+		if err := enc.Encode(entries); err != nil {
+			log.Panic("can not encode animals: ", err)
+		}
+	default:
+		for _, e := range entries {
+			fmt.Println(e.Animal)
+		}
+	}
+}
+
+// Questions and answers leading from n to target, nil if target is not in
+// subtree n
+func pathTo(n, target *node) []step {
+	if n == target {
+		return []step{}
+	}
+	if n.isLeaf() {
+		return nil
+	}
+	if p := pathTo(n.No, target); p != nil {
+		return append([]step{{n.Question, false}}, p...)
+	}
+	if p := pathTo(n.Yes, target); p != nil {
+		return append([]step{{n.Question, true}}, p...)
+	}
+	return nil
+}
+
+// Answers of steps as a string of y and n, as expected by edit-question
+// -path
+func pathString(steps []step) string {
+	var b strings.Builder
+	for _, s := range steps {
+		if s.yes {
+			b.WriteByte('y')
+		} else {
+			b.WriteByte('n')
+		}
+	}
+	return b.String()
+}
+
+// Leaves named name, or if none leaves whose name is close to or contains
+// name
+func (d *database) search(name string) []*node {
+	if leaf := d.findAnimal(name); leaf != nil {
+		return []*node{leaf}
+	}
+	var found []*node
+	for _, leaf := range leaves(d.Root) {
+		a, b := fold(leaf.Animal), fold(name)
+		if strings.Contains(a, b) || editDistance(a, b) <= maxTypos(utf8.RuneCountInString(name)) {
+			found = append(found, leaf)
+		}
+	}
+	return found
+}
+
+func runFind(cmd *command, args []string) {
+	if len(args) != 2 {
+		cmd.fail("database and name expected")
+	}
+	d, err := loadDatabase(args[0])
+	if err != nil {
+		log.Panic("can not load db: ", err)
+	}
+	found := d.search(args[1])
+	if len(found) == 0 {
+		fmt.Fprintf(os.Stderr, "%s: no animal like %q\n", args[0], args[1])
+		os.Exit(1)
+	}
+	for i, leaf := range found {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%s:\n", leaf.Animal)
+		for _, s := range pathTo(d.Root, leaf) {
+			fmt.Printf("    %s\n", s)
+		}
+	}
+}
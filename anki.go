@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"log"
+	"os"
+	"strings"
+)
+
+func init() {
+	commands = append(commands, &command{
+		Name:  "anki",
+		Args:  "database-file",
+		Short: "print Anki deck asking animals from the questions leading to them",
+		Run:   runAnki,
+	})
+}
+
+// Text of card field, HTML being enabled and tabs separating fields
+func ankiField(s string) string {
+	return html.EscapeString(strings.Join(strings.Fields(s), " "))
+}
+
+// Print one note per animal in the text format imported by Anki: the
+// answers leading to the animal on the front, the animal with its
+// description and picture if any on the back and the category as tag.
+func runAnki(cmd *command, args []string) {
+	if len(args) != 1 {
+		cmd.fail("database expected")
+	}
+	d, err := loadDatabase(args[0])
+	if err != nil {
+		log.Panic("can not load db: ", err)
+	}
+	w := bufio.NewWriter(os.Stdout)
+	fmt.Fprint(w, "#separator:tab\n#html:true\n#tags column:3\n")
+	tag := strings.ReplaceAll(d.category(), " ", "_")
+	for _, leaf := range leaves(d.Root) {
+		var clues []string
+		for _, s := range pathTo(d.Root, leaf) {
+			clues = append(clues, ankiField(s.String()))
+		}
+		if len(clues) == 0 {
+			continue
+		}
+		back := ankiField(leaf.Animal)
+		if leaf.Description != "" {
+			back += "<br>" + ankiField(leaf.Description)
+		}
+		if leaf.ImageURL != "" {
+			back += fmt.Sprintf(`<br><img src="%s">`, html.EscapeString(leaf.ImageURL))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", strings.Join(clues, "<br>"), back, tag)
+	}
+	if err := w.Flush(); err != nil {
+		log.Panic("can not write deck: ", err)
+	}
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Words accepted as yes or no, by language.  Words of English and of the
+// language of the player are accepted.  The user config can add more (see
+// config.go).
+var answerWords = map[string]struct{ yes, no []string }{
+	"en": {[]string{"yes", "y", "yep", "yeah", "yup", "sure"}, []string{"no", "n", "nope", "nah"}},
+	"fr": {[]string{"oui", "o", "ouais"}, []string{"non"}},
+	"de": {[]string{"ja", "j", "jawohl"}, []string{"nein"}},
+	"es": {[]string{"sí", "si"}, []string{"no"}},
+}
+
+// Shortest word tolerating any typo.  Shorter words are one typo away from
+// words of the other answer or of other meanings, e.g. hope and nope, so
+// they only tolerate two swapped letters, e.g. yse.
+const fuzzyMinLength = 5
+
+// Languages whose answer words are accepted
+func answerLanguages() []string {
+	if lang := language(); lang != "" && lang != defaultLanguage {
+		return []string{defaultLanguage, lang}
+	}
+	return []string{defaultLanguage}
+}
+
+// Interpret answer to yes-or-no question, tolerating one typo in longer
+// words and swapped letters in shorter ones
+func parseYesNo(s string) (yes, ok bool) {
+	s = fold(s)
+	c := settings()
+	isYes, isNo := matchWord(s, c.Yes, 0), matchWord(s, c.No, 0)
+	if isYes != isNo {
+		return isYes, true
+	}
+	langs := answerLanguages()
+	for _, lang := range langs {
+		words := answerWords[lang]
+		isYes = isYes || matchWord(s, words.yes, 0)
+		isNo = isNo || matchWord(s, words.no, 0)
+	}
+	if isYes || isNo {
+		return isYes, true
+	}
+	for _, lang := range langs {
+		words := answerWords[lang]
+		isYes = isYes || matchWord(s, words.yes, 1)
+		isNo = isNo || matchWord(s, words.no, 1)
+	}
+	if isYes == isNo {
+		return false, false
+	}
+	return isYes, true
+}
+
+// Whether s is at most maxDist edits away from one of words
+func matchWord(s string, words []string, maxDist int) bool {
+	for _, w := range words {
+		w = fold(w)
+		if maxDist > 0 && (len([]rune(w)) < fuzzyMinLength || len([]rune(s)) < fuzzyMinLength) {
+			if swapped(s, w) {
+				return true
+			}
+			continue
+		}
+		if editDistance(s, w) <= maxDist {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether a is b with two adjacent runes swapped
+func swapped(a, b string) bool {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) != len(rb) {
+		return false
+	}
+	for i := 0; i+1 < len(ra); i++ {
+		if ra[i] != rb[i] {
+			return ra[i] == rb[i+1] && ra[i+1] == rb[i] && string(ra[i+2:]) == string(rb[i+2:])
+		}
+	}
+	return false
+}
+
+// Number of insertions, deletions, substitutions and transpositions of
+// adjacent runes turning a into b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
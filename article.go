@@ -0,0 +1,88 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Words starting with a vowel letter but a consonant sound and conversely
+var (
+	consonantSounds = []string{"uni", "use", "usu", "uti", "eu", "one", "once"}
+	vowelSounds     = []string{"hour", "honest", "honor", "honour", "heir"}
+)
+
+// "a" or "an" depending on how the English word w starts
+func englishArticle(w string) string {
+	w = fold(w)
+	for _, p := range vowelSounds {
+		if strings.HasPrefix(w, p) {
+			return "an"
+		}
+	}
+	for _, p := range consonantSounds {
+		if strings.HasPrefix(w, p) {
+			return "a"
+		}
+	}
+	if w != "" && strings.ContainsRune("aeiou", rune(w[0])) {
+		return "an"
+	}
+	return "a"
+}
+
+// Name preceded by an indefinite article in the selected language.
+// Catalogs may translate whole phrases such as "an animal" to get the
+// gender right, and otherwise translate "a %s".
+func indefinite(name string) string {
+	phrase := englishArticle(name) + " " + name
+	if t := tr(phrase); t != phrase {
+		return t
+	}
+	if lang := language(); lang != "" && lang != defaultLanguage {
+		return fmt.Sprintf(tr("a %s"), tr(name))
+	}
+	return phrase
+}
+
+// Name of leaf content as used in prompts, with an article if the category
+// needs one
+func (d *database) named(name string) string {
+	if d.phrasing().article {
+		return indefinite(name)
+	}
+	return name
+}
+
+// Question guessing the content of leaf n, unless overridden by the node
+func (d *database) guessPrompt(n *node) string {
+	lang := language()
+	if lang == "" {
+		lang = defaultLanguage
+	}
+	if q, ok := n.Guess[lang]; ok {
+		return q
+	}
+	return fmt.Sprintf(d.phrasing().guess, d.named(n.localized()))
+}
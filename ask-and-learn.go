@@ -25,17 +25,28 @@ package main
 
 import (
 	"bufio"
-	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
 // Known animals are stored in a binary tree that grows over time
 type node struct {
+	// Stable identifier used by the op-log to designate nodes across
+	// replicas (see oplog.go).
+	ID string `json:",omitempty"`
+
+	// In files, ID of the shared node standing here (see dag.go)
+	Ref string `json:",omitempty"`
+
 	// Non-leaves store yes-or-no questions partitioning the animals stored
 	// in the children into two sets.
 	Question string
@@ -45,164 +56,774 @@ type node struct {
 
 	// Children
 	No, Yes *node
+
+	// Number of times players answered the question (see stats.go)
+	NoCount, YesCount int `json:",omitempty"`
+
+	// Number of times players chose the animal and Unix time of the last
+	// one
+	ChosenCount int   `json:",omitempty"`
+	LastChosen  int64 `json:",omitempty"`
+
+	// Number of times the animal was proposed
+	GuessCount int `json:",omitempty"`
+
+	// Number of times players found the question confusing (see feedback.go)
+	ConfusingCount int `json:",omitempty"`
+
+	// Phrasings of the question tried in turn (see phrasing.go)
+	Variants []variant `json:",omitempty"`
+
+	// Question or animal in other languages (see translate.go)
+	Translations map[string]string `json:",omitempty"`
+
+	// Guess question by language overriding the category one, e.g. to get
+	// the gender right (see article.go)
+	Guess map[string]string `json:",omitempty"`
+
+	// Why moderation asked to review the question or animal
+	Flagged string `json:",omitempty"`
+
+	// One-line description of the animal (see facts.go and wiki.go)
+	Description string `json:",omitempty"`
+
+	// Picture of the animal shown by consoles able to (see image.go)
+	ImageURL string `json:",omitempty"`
 }
 
 func (n *node) isLeaf() bool {
 	return n.Animal != ""
 }
 
-// Tree root
-var root *node
+// Loaded databases and the one being played
+var (
+	dbs []*database
+	db  *database
+)
 
 // Default initial tree content when creating new database
-var defaultRoot = node{Animal: "platypus"}
+var defaultRoot = node{ID: "r", Animal: "platypus"}
 
 // Command-line arguments and flags
 var (
 	createDbFlag = flag.Bool("c", false, "create new DB")
-	dbPath       string
+	categoryFlag = flag.String("category", defaultCategory, "kind of things to guess in new DB (with -c)")
+	popularFlag  = flag.Bool("popular", true, "guess popular animals before reaching them")
+	earlySize    = flag.Int("early-size", 0, "guess when this many animals or fewer remain (0: never)")
+	maxGuesses   = flag.Int("guesses", 3, "maximum number of guesses before reaching a leaf")
+	nearby       = flag.Int("nearby", 0, "number of animals of the other branch of the last question to guess before learning")
+	trailFlag    = flag.Bool("trail", false, "show questions and answers leading to guess")
+	playerFlag   = flag.String("player", "", "name of player whose profile records games")
+	marathon     = flag.Int("marathon", 0, "play this many games and score the program")
+	marathonLog  = flag.String("marathon-log", "", "CSV file to append marathon results to")
+	matchGames   = flag.Int("match", 0, "number of games of hotseat match (default: two per player)")
+	hintsFlag    = flag.Bool("hints", false, "show number of remaining candidates after each answer")
+	maxQuestions = flag.Int("max-questions", 0, "give up after this many questions (0: no limit)")
+	syncURL      = flag.String("sync-url", "", "URL to POST saved DB to after each session")
+	dbPaths      []string
 )
 
 var stdin *bufio.Reader
 
+// Subcommand invoked as "ask-and-learn name [flags] args...".  Without
+// subcommand, the program plays with the database given on the command line.
+type command struct {
+	Name  string
+	Args  string // synopsis of positional arguments
+	Short string // one-line description
+	Flag  flag.FlagSet
+	Run   func(cmd *command, args []string)
+}
+
+// Registered subcommands, populated by init() functions
+var commands []*command
+
+func lookupCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.Name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// Parse subcommand flags and run it
+func (cmd *command) run(args []string) {
+	cmd.Flag.Init(cmd.Name, flag.ExitOnError)
+	cmd.Flag.Usage = cmd.usage
+	cmd.Flag.Parse(args)
+	cmd.Run(cmd, cmd.Flag.Args())
+}
+
+func (cmd *command) usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s %s %s\n", path.Base(os.Args[0]), cmd.Name, cmd.Args)
+	cmd.Flag.PrintDefaults()
+}
+
+// Report bad command-line and exit
+func (cmd *command) fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	cmd.usage()
+	os.Exit(1)
+}
+
 func main() {
+	settings() // report bad settings before anything else
+	if len(os.Args) > 1 {
+		if cmd := lookupCommand(os.Args[1]); cmd != nil {
+			cmd.run(os.Args[2:])
+			return
+		}
+	}
+
 	parseCmdLine()
 	stdin = bufio.NewReader(os.Stdin)
-	initTree()
+	initTrees()
+	atEndOfInput = endSession
 	playGames()
-	saveTree()
+	endSession()
+}
+
+// Save what was learned
+func endSession() {
+	saveTrees()
+	if *syncURL != "" {
+		backupTrees()
+	}
+	waitHooks()
 }
 
 func parseCmdLine() {
+	flag.Var(&players, "players", "comma-separated names of hotseat players")
 	flag.Usage = usage
 	flag.Parse()
-	if flag.NArg() != 1 {
+	if flag.NArg() < 1 {
 		fmt.Fprintf(os.Stderr, "database expected\n")
 		usage()
 		os.Exit(1)
 	}
-	dbPath = flag.Arg(0)
+	dbPaths = flag.Args()
+	if *overlayFlag != "" && len(dbPaths) > 1 {
+		fmt.Fprintf(os.Stderr, "-overlay needs a single database\n")
+		os.Exit(1)
+	}
+	switch *timeoutAnswer {
+	case "", "yes", "no":
+	default:
+		fmt.Fprintf(os.Stderr, "-timeout-answer expects yes or no, not %q\n", *timeoutAnswer)
+		os.Exit(1)
+	}
+	checkListenCommand()
+	applyDifficulty()
 }
 
 func usage() {
-	fmt.Fprintf(os.Stderr, "usage: %s [-c] database-file\n", path.Base(os.Args[0]))
+	prog := path.Base(os.Args[0])
+	fmt.Fprintf(os.Stderr, "usage: %s [flags] database-file...\n", prog)
 	flag.PrintDefaults()
+	if len(commands) > 0 {
+		fmt.Fprintf(os.Stderr, "\n   or: %s command [flags] args...\n\ncommands:\n", prog)
+		sorted := make([]*command, len(commands))
+		copy(sorted, commands)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+		for _, cmd := range sorted {
+			fmt.Fprintf(os.Stderr, "  %-12s %s\n", cmd.Name, cmd.Short)
+		}
+	}
+}
+
+// Populate the knowledge trees from user-specified files or create them from
+// scratch
+func initTrees() {
+	for _, path := range dbPaths {
+		dbs = append(dbs, initTree(path))
+	}
+	db = dbs[0]
+	if *overlayFlag != "" {
+		db.applyOverlay(*overlayFlag)
+	}
 }
 
-// Populate the knowledge tree from user-specified file or create it from scratch
-func initTree() {
+func initTree(path string) *database {
 	if *createDbFlag {
-		root = &defaultRoot
-	} else {
-		content, err := ioutil.ReadFile(dbPath)
-		if err != nil {
-			log.Panic("can not read db:", err)
+		p, ok := categories[*categoryFlag]
+		first := defaultRoot
+		if ok {
+			first.Animal = p.first
+		} else {
+			first.Animal = ask(tr("Name %s to start with:"), indefinite(*categoryFlag))
 		}
-		root = new(node)
-		err = json.Unmarshal(content, root)
-		if err != nil {
-			log.Panic("can not marshal db:", err)
+		d := newDatabase(&first)
+		if *categoryFlag != defaultCategory {
+			d.Category = *categoryFlag
+		}
+		if lang := language(); lang != "" && lang != defaultLanguage {
+			d.Language = lang
 		}
+		return d
 	}
+	d, err := loadDatabase(path)
+	if err != nil {
+		log.Panic("can not load db: ", err)
+	}
+	return d
 }
 
-// Save tree to user-specified file
-func saveTree() {
-	content, err := json.MarshalIndent(root, "", "    ")
-	if err != nil {
-		log.Panic("can not unmarshal db:", err)
+// Save trees to user-specified files
+func saveTrees() {
+	for i, d := range dbs {
+		var err error
+		if d.personal != nil {
+			err = d.saveWithOverlay(dbPaths[i], *overlayFlag)
+		} else {
+			err = d.save(dbPaths[i])
+		}
+		if err != nil {
+			log.Panic("can not save db: ", err)
+		}
 	}
+}
 
-	err = ioutil.WriteFile(dbPath, content, 0700)
-	if err != nil {
-		log.Panic("can not write db:", err)
+// Send saved trees to user-specified URL
+func backupTrees() {
+	for _, path := range dbPaths {
+		err := upload(*syncURL, path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "can not sync %s to %s: %s\n", path, *syncURL, err)
+		}
 	}
 }
 
 // Play until user bored
 func playGames() {
+	checkOutputFlag()
+	if len(players) > 0 {
+		playMatch()
+		return
+	}
+	if *marathon > 0 {
+		playMarathon(*marathon)
+		return
+	}
+	pausable = true
+	if *quietFlag {
+		out = ioutil.Discard
+		printResult(playOneGame(*playerFlag, ""))
+		return
+	}
 	again := true
 	for again {
-		playOneGame()
-		again = askYesNo("Play another game?")
+		g := playOneGame(*playerFlag, "")
+		printResult(g)
+		again = !g.paused && askYesNo("%s", tr("Play another game?"))
+	}
+}
+
+// Play game and update profile of named player if any.  Animals to learn
+// are taught by teacher if not empty.
+func playOneGame(player, teacher string) *game {
+	if len(dbs) > 1 {
+		chooseDatabase()
+	}
+	var ui console = terminal{}
+	if *kidsFlag {
+		ui = kidsConsole{ui}
+	}
+	g := newGame(db, ui)
+	g.teacher = teacher
+	if *resumeFlag {
+		g.resume(player)
+	}
+	showBanner(output().start())
+	g.play()
+	if g.paused {
+		g.db.pause(player, g)
+		g.ui.tell(tr("Game paused, play with -resume to continue it."))
+		return g
+	}
+	if g.abandoned {
+		return g
+	}
+	showBanner(output().end(g))
+	if player != "" {
+		for _, a := range g.db.profile(player).record(g) {
+			g.ui.tell(fmt.Sprintf(tr("Achievement unlocked for %s: %s!"), player, tr(a.title)))
+		}
+	}
+	if teacher != "" {
+		for _, a := range g.db.profile(teacher).recordTeaching(g) {
+			g.ui.tell(fmt.Sprintf(tr("Achievement unlocked for %s: %s!"), teacher, tr(a.title)))
+		}
+	}
+	return g
+}
+
+// Player-facing side of a game
+type console interface {
+	// Ask question and return non-empty answer
+	ask(prompt string) string
+
+	// Ask question expecting yes or no answer
+	askYesNo(prompt string) bool
+
+	// Show message to player
+	tell(msg string)
+}
+
+// Console able to tell guesses from other questions
+type guesser interface {
+	askGuess(prompt string) bool
+}
+
+func (g *game) askGuess(prompt string) bool {
+	if gs, ok := g.ui.(guesser); ok {
+		return gs.askGuess(prompt)
+	}
+	return g.ui.askYesNo(prompt)
+}
+
+// Propose animal of leaf to player
+func (g *game) guess(leaf *node) bool {
+	g.showImage(leaf)
+	leaf.GuessCount++
+	if !g.askGuess(g.db.guessPrompt(leaf)) {
+		g.notify(guessFailed, leaf)
+		return false
 	}
+	g.notify(guessCorrect, leaf)
+	return true
+}
+
+// Console reading stdin and writing stdout
+type terminal struct{}
+
+func (terminal) ask(prompt string) string    { return askAs(textPrompt, prompt) }
+func (terminal) askYesNo(prompt string) bool { return askYesNoTimed(questionPrompt, prompt) }
+func (terminal) askGuess(prompt string) bool { return askYesNoTimed(guessPrompt, prompt) }
+
+func (terminal) tell(msg string) {
+	fmt.Fprintln(out, output().message(msg))
+	speak(msg)
 }
 
-func playOneGame() {
-	n := root
+// State of game in progress
+type game struct {
+	db *database
+	ui console
+
+	// Animals guessed before reaching their leaf and rejected
+	rejected map[*node]bool
+
+	// Number of questions answered so far
+	questions int
+
+	// Questions answered so far in the order asked, their nodes and the
+	// node reached, nil before the first answer
+	path  []step
+	asked []*node
+	at    *node
+
+	// Texts taught in this game that moderation flagged, with the reason
+	flagged map[string]string
+
+	// Index of variant asked by question node
+	phrased map[*node]int
+
+	// Summary of subtrees explored, see summarize
+	summaries map[*node]summary
+
+	// Player teaching animals in hotseat matches, empty if the player
+	// answering teaches them
+	teacher string
+
+	// Outcome: leaf of animal chosen by player, whether it was found and
+	// whether it had to be taught
+	answer    *node
+	found     bool
+	taught    bool
+	forfeited bool
+	paused    bool
+	abandoned bool
+}
+
+func newGame(d *database, ui console) *game {
+	return &game{db: d, ui: ui, rejected: make(map[*node]bool), phrased: make(map[*node]int),
+		summaries: make(map[*node]summary)}
+}
+
+// Question and answer given to it
+type step struct {
+	question string
+	yes      bool
+}
+
+// Play game until program finds animal or learns it, or player forfeits
+func (g *game) play() {
+	playing = true
+	defer func() {
+		playing = false
+		switch err := recover(); err {
+		case nil:
+		case errQuit:
+			g.abandoned = true
+			g.ui.tell(tr("Game abandoned."))
+		case errGiveUp:
+			g.abandoned = true
+			g.offerToLearn()
+		case errPause:
+			g.paused = true
+		case errForfeit:
+			g.forfeited = true
+			g.ui.tell(tr("Time is up, you lose this game!"))
+		default:
+			panic(err)
+		}
+	}()
+	g.notify(gameStarted, nil)
+	g.explore()
+	g.scorePhrasings()
+	g.funFact()
+	g.askFeedback()
+}
+
+func (g *game) explore() {
+	n := g.current()
 
 	for !n.isLeaf() {
-		yes := askYesNo(n.Question)
-		if yes {
-			n = n.Yes
+		if g.guessEarly(n) {
+			g.showTrail()
+			return
+		}
+		if *maxQuestions > 0 && g.questions >= *maxQuestions {
+			g.giveUp(n)
+			return
+		}
+		if *progressFlag {
+			g.showProgress(n)
+		}
+		if twin(n) && random().Intn(2) == 0 {
+			n = g.askTwinFirst(n)
 		} else {
-			n = n.No
+			n = g.askNode(n)
+		}
+		g.at = n
+		if *hintsFlag {
+			g.hint(n)
+		}
+	}
+
+	if !g.rejected[n] {
+		g.found = g.guess(n)
+	}
+	if !g.found {
+		if leaf := g.guessNearby(n); leaf != nil {
+			n = leaf
+			g.found = true
+		}
+	}
+	g.showTrail()
+	if g.found {
+		n.choose()
+		g.answer = n
+	} else {
+		g.answer = g.learnNewAnimal(n)
+	}
+}
+
+// Ask question of n and record answer.  Returns child reached.
+func (g *game) askNode(n *node) *node {
+	question := g.phrase(n)
+	yes := g.ui.askYesNo(question)
+	g.questions++
+	g.path = append(g.path, step{question, yes})
+	g.asked = append(g.asked, n)
+	n.recordAnswer(yes)
+	if yes {
+		return n.Yes
+	}
+	return n.No
+}
+
+// Print answers that led to guess if requested
+func (g *game) showTrail() {
+	if !*trailFlag || len(g.path) == 0 {
+		return
+	}
+	g.ui.tell(g.trail())
+}
+
+func (g *game) trail() string {
+	var parts []string
+	for _, s := range g.path {
+		parts = append(parts, s.String())
+	}
+	return strings.Join(parts, " → ")
+}
+
+func (s step) String() string {
+	if s.yes {
+		return s.question + " " + tr("yes")
+	}
+	return s.question + " " + tr("no")
+}
+
+// Tell how many animals remain possible when reaching n
+func (g *game) hint(n *node) {
+	left := g.summarize(n).leaves
+	for leaf := range g.rejected {
+		if leaf.isDescendantOf(n) {
+			left--
 		}
 	}
+	if left == 1 {
+		g.ui.tell(tr("1 candidate left"))
+	} else {
+		g.ui.tell(fmt.Sprintf(tr("%d candidates left"), left))
+	}
+}
+
+// Stop exploring subtree n and learn the animal chosen by the player
+func (g *game) giveUp(n *node) {
+	g.answer = g.learnAnimal(n, g.askName(tr("I give up! What was it?")))
+}
 
-	found := askYesNo("Is it a %s?", n.Animal)
-	if !found {
-		learnNewAnimal(n)
+// Popular animals are guessed as soon as their confidence reaches
+// popularConfidence, provided their subtree recorded at least
+// popularMinChoices choices.
+const (
+	popularConfidence = 0.5
+	popularMinChoices = 5
+)
+
+// Try best candidates of subtree n when it is small or one of them is likely
+// enough.  Returns true if one was right.
+func (g *game) guessEarly(n *node) bool {
+	sum := g.summarize(n)
+	small := sum.leaves <= *earlySize
+	confident := *popularFlag && sum.choices >= popularMinChoices && sum.confidence() >= popularConfidence
+	if !small && !confident {
+		return false
+	}
+	cs, _ := n.candidates()
+	for _, c := range cs {
+		if len(g.rejected) >= *maxGuesses || !small && c.confidence < popularConfidence {
+			break
+		}
+		if g.rejected[c.leaf] {
+			continue
+		}
+		if g.guess(c.leaf) {
+			c.leaf.choose()
+			g.answer = c.leaf
+			g.found = true
+			return true
+		}
+		g.rejected[c.leaf] = true
 	}
+	return false
+}
+
+// Try the most likely animals of the branch the last question leading to
+// leaf ruled out, in case the player answered it differently than the
+// player who taught it.  Returns the leaf found, nil if none.
+func (g *game) guessNearby(leaf *node) *node {
+	parent := parentOf(g.db.Root, leaf)
+	if *nearby <= 0 || parent == nil {
+		return nil
+	}
+	other := parent.Yes
+	if other == leaf {
+		other = parent.No
+	}
+	cs, _ := other.candidates()
+	tries := 0
+	for _, c := range cs {
+		if tries >= *nearby {
+			break
+		}
+		if g.rejected[c.leaf] {
+			continue
+		}
+		tries++
+		if g.guess(c.leaf) {
+			return c.leaf
+		}
+		g.rejected[c.leaf] = true
+	}
+	return nil
+}
+
+// Let user pick database to play against
+func chooseDatabase() {
+	for i, path := range dbPaths {
+		fmt.Fprintf(out, "%d) %s (%s)\n", i+1, dbName(path), tr(dbs[i].category()))
+	}
+	for {
+		s := ask("%s", tr("Which one do you want to play with?"))
+		for i, path := range dbPaths {
+			if s == strconv.Itoa(i+1) || s == dbName(path) || s == dbs[i].category() {
+				db = dbs[i]
+				return
+			}
+		}
+	}
+}
+
+// Short name of database stored in file
+func dbName(file string) string {
+	return strings.TrimSuffix(path.Base(file), path.Ext(file))
 }
 
 // Ask user how to distinguish n.Animal from user-chosen one and update tree
-func learnNewAnimal(n *node) {
-	animal := ask("What is the animal I failed to find?")
-	leaf := &node{Animal: animal}
-	question := ask("What question can distinguish a %s from a %s?", animal, n.Animal)
-	isYesLeaf := askYesNo("What answer is expected for a %s?", animal)
-	mutateIntoQuestionNode(n, question, leaf, isYesLeaf)
+func (g *game) learnNewAnimal(n *node) *node {
+	p := g.db.phrasing()
+	return g.learnAnimal(n, g.askName(fmt.Sprintf(p.unknown, tr(g.db.category()))))
+}
+
+// Insert animal above n, asking user how to distinguish it from the animals
+// of the subtree.  Returns new leaf, which is not in tree if it is full, or
+// the leaf of the animal if already known.
+func (g *game) learnAnimal(n *node, animal string) *node {
+	var summary, image string
+	if *wikipediaFlag {
+		animal, summary, image = g.lookUp(animal)
+	}
+	if known := g.db.findAnimal(animal); known != nil {
+		known.choose()
+		g.ui.tell(fmt.Sprintf(tr("I know the %s, I should have found it."), known.localized()))
+		return known
+	}
+	p := g.db.phrasing()
+	leaf := &node{Animal: animal, Description: summary, ImageURL: image}
+	leaf.choose()
+	if g.db.full() {
+		g.ui.tell(tr("My memory is full, I can not learn anything new."))
+		return leaf
+	}
+	if g.teacher != "" {
+		g.ui.tell(fmt.Sprintf(tr("Pass the keyboard to %s, who will teach me the %s."), g.teacher, animal))
+	}
+	question := g.askQuestion(animal, n)
+	isYesLeaf := g.ui.askYesNo(fmt.Sprintf(p.expected, g.db.named(animal)))
+	if g.teacher != "" {
+		g.ui.tell(tr("Thanks! Pass the keyboard back."))
+	}
+	g.db.learn(n, leaf, question, isYesLeaf)
+	g.taught = true
+	g.notify(animalLearned, leaf)
+	g.contribute(animal, question, isYesLeaf)
+	g.db.noteLanguage(n)
+	g.db.noteLanguage(leaf)
+	g.flag(n)
+	g.flag(leaf)
+	if *factsFlag {
+		g.askFact(leaf)
+	}
+	return g.db.prune(leaf)
 }
 
-// Turn leaf node into a question node
+// Number of animals named when describing a subtree
+const describedAnimals = 3
+
+// Animal of leaf or list of the most likely animals of subtree
+func (d *database) describeSubtree(n *node) string {
+	if n.isLeaf() {
+		return d.named(n.localized())
+	}
+	cs, _ := n.candidates()
+	var names []string
+	for i, c := range cs {
+		if i == describedAnimals {
+			names = append(names, tr("others"))
+			break
+		}
+		names = append(names, d.named(c.leaf.localized()))
+	}
+	last := len(names) - 1
+	return strings.Join(names[:last], ", ") + " " + tr("or") + " " + names[last]
+}
+
+// Turn node into a question node whose children are leaf and a copy of the
+// former content of n
 func mutateIntoQuestionNode(n *node, question string, leaf *node, isYesLeaf bool) {
-	otherLeaf := &node{Animal: n.Animal}
-	n.Animal = ""
-	n.Question = question
+	other := new(node)
+	*other = *n
+	*n = node{ID: other.ID, Question: question}
 	if isYesLeaf {
 		n.Yes = leaf
-		n.No = otherLeaf
+		n.No = other
 	} else {
 		n.No = leaf
-		n.Yes = otherLeaf
+		n.Yes = other
 	}
 }
 
 // Ask question expecting yes or no answer
-func askYesNo(prompt string, args ...interface{}) (yes bool) {
-	done := false
-	for !done {
-		s := ask(prompt, args...)
-		switch s {
-		case "yes", "y":
-			yes = true
-			done = true
-		case "no", "n":
-			yes = false
-			done = true
-		default:
-			// nop
+func askYesNo(prompt string, args ...interface{}) bool {
+	return askYesNoAs(yesNoPrompt, fmt.Sprintf(prompt, args...))
+}
+
+func askYesNoAs(kind promptKind, prompt string) bool {
+	for {
+		answer := askAs(kind, prompt)
+		if kind != yesNoPrompt {
+			checkPause(answer)
+		}
+		if yes, ok := parseYesNo(answer); ok {
+			return yes
 		}
+		rejectAnswer(tr("Please answer yes or no."))
 	}
-	return
 }
 
 // Ask question to user
 func ask(prompt string, args ...interface{}) string {
-	prompt += " "
+	return askAs(textPrompt, fmt.Sprintf(prompt, args...))
+}
+
+func askAs(kind promptKind, prompt string) string {
+	speak(prompt)
+	prompt = output().prompt(kind, prompt)
 	for {
-		fmt.Printf(prompt, args...)
-		answer, err := stdin.ReadString('\n')
+		showPrompt(prompt)
+		answer, err := readLine()
+		if err == io.EOF {
+			endOfInput()
+		}
 		if err != nil {
 			log.Panic("error when reading stdin:", err)
 		}
-		if len(answer) > 0 && answer[len(answer)-1] == '\n' {
-			answer = answer[:len(answer)-1]
-		}
+		answer = compose(trimLine(answer))
+		echoAnswer(answer)
+		checkQuit(answer)
 		if len(answer) > 0 {
 			return answer
 		}
+		rejectAnswer("")
+	}
+}
+
+// Run before exiting when input ends, e.g. to save changes
+var atEndOfInput func()
+
+func endOfInput() {
+	fmt.Fprintln(out)
+	if atEndOfInput != nil {
+		atEndOfInput()
+	}
+	os.Exit(0)
+}
+
+// Strip end of line, whether \n or \r\n, and surrounding blanks.  Lines
+// that are not valid UTF-8 come from consoles using a legacy code page and
+// are read as Latin-1.
+func trimLine(s string) string {
+	if !utf8.ValidString(s) {
+		runes := make([]rune, len(s))
+		for i := 0; i < len(s); i++ {
+			runes[i] = rune(s[i])
+		}
+		s = string(runes)
 	}
-	return ""
+	return strings.TrimSpace(strings.TrimPrefix(s, "\uFEFF"))
 }
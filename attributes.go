@@ -0,0 +1,113 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Truthful answers to questions for a set of animals, read from a CSV file
+// whose header row is "animal,question1,question2,..." and whose other rows
+// hold an animal name followed by yes, no or nothing for each question (1
+// and 0 or true and false also do, as found in public datasets).
+type attributeTable struct {
+	questions []string // in header order
+	answers   map[string]map[string]bool
+	animals   []string // in file order
+}
+
+func loadAttributes(path string) (*attributeTable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%s: can not read header: %s", path, err)
+	}
+	t := &attributeTable{questions: header[1:], answers: make(map[string]map[string]bool)}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+		animal := strings.TrimSpace(record[0])
+		if animal == "" {
+			continue
+		}
+		if _, dup := t.answers[animal]; !dup {
+			t.animals = append(t.animals, animal)
+		}
+		answers := make(map[string]bool)
+		for i, field := range record[1:] {
+			if i >= len(t.questions) {
+				break
+			}
+			switch strings.ToLower(strings.TrimSpace(field)) {
+			case "yes", "y", "1", "true":
+				answers[t.questions[i]] = true
+			case "no", "n", "0", "false":
+				answers[t.questions[i]] = false
+			case "":
+			default:
+				return nil, fmt.Errorf("%s: %s: bad answer %q", path, animal, field)
+			}
+		}
+		t.answers[animal] = answers
+	}
+	return t, nil
+}
+
+// Answer to question for animal if known
+func (t *attributeTable) answer(animal, question string) (yes, ok bool) {
+	if t == nil {
+		return false, false
+	}
+	yes, ok = t.answers[animal][question]
+	return
+}
+
+// First question answered differently for a and b.  Returns answer for a.
+func (t *attributeTable) distinguish(a, b string) (question string, yesForA, ok bool) {
+	if t == nil {
+		return "", false, false
+	}
+	for _, q := range t.questions {
+		ya, oka := t.answer(a, q)
+		yb, okb := t.answer(b, q)
+		if oka && okb && ya != yb {
+			return q, ya, true
+		}
+	}
+	return "", false, false
+}
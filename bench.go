@@ -0,0 +1,170 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Performance measurements comparable across releases and file formats:
+// time to load and save the database, and throughput of games played by a
+// simulated player thinking of a random animal and answering as the path
+// leading to it says.  The player is seeded so that runs on the same
+// database play the same games.
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"os"
+	"runtime"
+	"time"
+)
+
+func init() {
+	cmd := &command{
+		Name:  "bench",
+		Args:  "database-file",
+		Short: "measure load, save and game throughput",
+		Run:   runBench,
+	}
+	benchRuns = cmd.Flag.Int("runs", 5, "number of loads and saves to average")
+	benchGames = cmd.Flag.Int("games", 100000, "number of simulated games")
+	commands = append(commands, cmd)
+}
+
+var (
+	benchRuns  *int
+	benchGames *int
+)
+
+// Time taken by f on average over runs calls
+func timeRuns(runs int, f func()) time.Duration {
+	start := time.Now()
+	for i := 0; i < runs; i++ {
+		f()
+	}
+	return time.Since(start) / time.Duration(runs)
+}
+
+// Bytes allocated by f
+func allocated(f func()) uint64 {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	f()
+	runtime.ReadMemStats(&after)
+	return after.TotalAlloc - before.TotalAlloc
+}
+
+func mib(bytes uint64) float64 {
+	return float64(bytes) / (1 << 20)
+}
+
+// Play games, each with a random animal of d.  Returns number of questions
+// asked and of animals not found.
+func simulateGames(d *database, games int) (questions, lost int) {
+	animals := leaves(d.Root)
+	answers := make([]map[*node]bool, len(animals))
+	for i, leaf := range animals {
+		answers[i] = make(map[*node]bool)
+		n := d.Root
+		for _, s := range pathTo(d.Root, leaf) {
+			answers[i][n] = s.yes
+			if s.yes {
+				n = n.Yes
+			} else {
+				n = n.No
+			}
+		}
+	}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < games; i++ {
+		k := rng.Intn(len(animals))
+		n := d.Root
+		for !n.isLeaf() {
+			yes, ok := answers[k][n]
+			if !ok {
+				yes = rng.Intn(2) == 0
+			}
+			if yes {
+				n = n.Yes
+			} else {
+				n = n.No
+			}
+			questions++
+		}
+		if n != animals[k] {
+			lost++
+		}
+	}
+	return questions, lost
+}
+
+func runBench(cmd *command, args []string) {
+	if len(args) != 1 {
+		cmd.fail("database expected")
+	}
+	if *benchRuns <= 0 || *benchGames <= 0 {
+		cmd.fail("positive -runs and -games expected")
+	}
+	info, err := os.Stat(args[0])
+	if err != nil {
+		log.Panic("can not load db: ", err)
+	}
+	var d *database
+	load := func() {
+		d, err = loadDatabase(args[0])
+		if err != nil {
+			log.Panic("can not load db: ", err)
+		}
+	}
+	loadAlloc := allocated(load)
+	var mem runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&mem)
+	loadTime := timeRuns(*benchRuns, load)
+
+	f, err := ioutil.TempFile("", "ask-and-learn-bench-*.json")
+	if err != nil {
+		log.Panic("can not create temporary file: ", err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+	saveTime := timeRuns(*benchRuns, func() {
+		if err := d.save(f.Name()); err != nil {
+			log.Panic("can not save db: ", err)
+		}
+	})
+
+	start := time.Now()
+	questions, lost := simulateGames(d, *benchGames)
+	elapsed := time.Since(start)
+
+	fmt.Printf("database: %d animals, %d nodes, %.1f KiB\n", len(leaves(d.Root)), nodeCount(d.Root), float64(info.Size())/1024)
+	fmt.Printf("load: %v (%.1f MiB allocated)\n", loadTime, mib(loadAlloc))
+	fmt.Printf("save: %v\n", saveTime)
+	fmt.Printf("games: %d in %v, %.0f games/s, %.2f questions/game\n",
+		*benchGames, elapsed, float64(*benchGames)/elapsed.Seconds(), float64(questions)/float64(*benchGames))
+	if lost > 0 {
+		fmt.Printf("    %d game(s) reached another animal\n", lost)
+	}
+	fmt.Printf("memory: %.1f MiB in use after load\n", mib(mem.HeapAlloc))
+}
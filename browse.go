@@ -0,0 +1,142 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Read-only outline of a database whose branches can be expanded and
+// collapsed
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	commands = append(commands, &command{
+		Name:  "browse",
+		Args:  "database-file",
+		Short: "explore shape of tree without modifying it",
+		Run:   runBrowse,
+	})
+}
+
+type browser struct {
+	d      *database
+	open   map[*node]bool // expanded question nodes
+	marked map[*node]bool // search results
+	lines  []*node        // as last displayed
+}
+
+const browseHelp = `NUMBER   expand or collapse question
+/TEXT    show animals and questions containing TEXT
+all      expand everything
+none     collapse everything
+q        quit`
+
+func runBrowse(cmd *command, args []string) {
+	if len(args) != 1 {
+		cmd.fail("database expected")
+	}
+	d, err := loadDatabase(args[0])
+	if err != nil {
+		log.Panic("can not load db: ", err)
+	}
+	stdin = bufio.NewReader(os.Stdin)
+	b := &browser{d: d, open: map[*node]bool{d.Root: true}, marked: make(map[*node]bool)}
+	fmt.Println(`Type "help" for commands.`)
+	for {
+		b.display()
+		s := ask("browse>")
+		switch {
+		case s == "q" || s == "quit":
+			return
+		case s == "help" || s == "?":
+			fmt.Println(browseHelp)
+		case s == "all":
+			for _, n := range questionNodes(d.Root) {
+				b.open[n] = true
+			}
+		case s == "none":
+			b.open = make(map[*node]bool)
+		case strings.HasPrefix(s, "/"):
+			b.search(strings.TrimSpace(s[1:]))
+		default:
+			i, err := strconv.Atoi(s)
+			if err != nil || i < 1 || i > len(b.lines) || b.lines[i-1].isLeaf() {
+				fmt.Println("number of question expected, try help")
+				continue
+			}
+			n := b.lines[i-1]
+			b.open[n] = !b.open[n]
+		}
+	}
+}
+
+// Print visible nodes, numbering them
+func (b *browser) display() {
+	b.lines = nil
+	b.displayNode(b.d.Root, "", "")
+}
+
+func (b *browser) displayNode(n *node, indent, label string) {
+	b.lines = append(b.lines, n)
+	mark := " "
+	if b.marked[n] {
+		mark = "*"
+	}
+	switch {
+	case n.isLeaf():
+		fmt.Printf("%4d %s %s%s%s\n", len(b.lines), mark, indent, label, n.Animal)
+		return
+	case !b.open[n]:
+		fmt.Printf("%4d %s %s%s[+] %s (%d)\n", len(b.lines), mark, indent, label, n.Question, n.leafCount())
+		return
+	}
+	fmt.Printf("%4d %s %s%s[-] %s (%d)\n", len(b.lines), mark, indent, label, n.Question, n.leafCount())
+	b.displayNode(n.No, indent+"    ", tr("no")+": ")
+	b.displayNode(n.Yes, indent+"    ", tr("yes")+": ")
+}
+
+// Mark nodes containing text and expand their ancestors
+func (b *browser) search(text string) {
+	b.marked = make(map[*node]bool)
+	var found []*node
+	for _, n := range append(questionNodes(b.d.Root), leaves(b.d.Root)...) {
+		if strings.Contains(fold(n.text()), fold(text)) {
+			found = append(found, n)
+		}
+	}
+	if len(found) == 0 {
+		fmt.Printf("nothing contains %q\n", text)
+		return
+	}
+	for _, n := range found {
+		b.marked[n] = true
+		for p := parentOf(b.d.Root, n); p != nil; p = parentOf(b.d.Root, p) {
+			b.open[p] = true
+		}
+	}
+}
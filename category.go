@@ -0,0 +1,100 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Wording of the prompts that mention the things being guessed.  Format
+// verbs receive names of things stored in leaves, with an article if
+// needed (see article.go), except unknown which receives the category name.
+type phrasing struct {
+	guess       string // "Is it %s?"
+	unknown     string // "What is the %s I failed to find?"
+	distinguish string // "What question can distinguish %s from %s?"
+	expected    string // "What answer is expected for %s?"
+
+	// Whether names take an indefinite article
+	article bool
+
+	// Content of the initial leaf of new databases
+	first string
+}
+
+// Category used by databases not specifying one
+const defaultCategory = "animal"
+
+// Phrasing of known categories.  Other categories use genericPhrasing.
+var categories = map[string]*phrasing{
+	"animal": {
+		guess:       "Is it %s?",
+		unknown:     "What is the %s I failed to find?",
+		distinguish: "What question can distinguish %s from %s?",
+		expected:    "What answer is expected for %s?",
+		article:     true,
+		first:       defaultRoot.Animal,
+	},
+	"country": {
+		guess:       "Is it %s?",
+		unknown:     "What is the %s I failed to find?",
+		distinguish: "What question can distinguish %s from %s?",
+		expected:    "What answer is expected for %s?",
+		first:       "Australia",
+	},
+	"movie character": {
+		guess:       "Is it %s?",
+		unknown:     "Who is the %s I failed to find?",
+		distinguish: "What question can distinguish %s from %s?",
+		expected:    "What answer is expected for %s?",
+		first:       "Darth Vader",
+	},
+}
+
+var genericPhrasing = phrasing{
+	guess:       "Is it %s?",
+	unknown:     "What is the %s I failed to find?",
+	distinguish: "What question can distinguish %s from %s?",
+	expected:    "What answer is expected for %s?",
+}
+
+func (d *database) category() string {
+	if d.Category == "" {
+		return defaultCategory
+	}
+	return d.Category
+}
+
+func (d *database) phrasing() *phrasing {
+	p, ok := categories[d.category()]
+	if !ok {
+		p = &genericPhrasing
+	}
+	if *kidsFlag {
+		p = kidsPhrasing(p)
+	}
+	return &phrasing{
+		guess:       tr(p.guess),
+		unknown:     tr(p.unknown),
+		distinguish: tr(p.distinguish),
+		expected:    tr(p.expected),
+		article:     p.article,
+		first:       p.first,
+	}
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+)
+
+func init() {
+	commands = append(commands, &command{
+		Name:  "check",
+		Args:  "database-file",
+		Short: "report structural problems of hand-edited databases",
+		Run:   runCheck,
+	})
+}
+
+// Problems found in tree.  Loading rejects cycles (see dag.go), but duplicate
+// IDs make the op-log treat distinct nodes as one, which is as harmful.
+type checker struct {
+	problems []string
+	ids      map[string]string // path of node by ID
+	animals  map[string]string // path of leaf by folded name
+	seen     map[*node]bool    // shared nodes are checked once
+}
+
+func (c *checker) report(path, format string, args ...interface{}) {
+	c.problems = append(c.problems, fmt.Sprintf("[%s] ", path)+fmt.Sprintf(format, args...))
+}
+
+func (c *checker) walk(n *node, path string) {
+	if c.seen[n] {
+		return
+	}
+	c.seen[n] = true
+	if n.ID != "" {
+		if other, dup := c.ids[n.ID]; dup {
+			c.report(path, "ID %q also used at [%s]", n.ID, other)
+		}
+		c.ids[n.ID] = path
+	}
+	if n.isLeaf() {
+		if n.Question != "" {
+			c.report(path, "both animal %q and question %q", n.Animal, n.Question)
+		}
+		if n.No != nil || n.Yes != nil {
+			c.report(path, "animal %q has children", n.Animal)
+		}
+		if other, dup := c.animals[fold(n.Animal)]; dup {
+			c.report(path, "%s also at [%s]", n.Animal, other)
+		}
+		c.animals[fold(n.Animal)] = path
+		return
+	}
+	if n.Question == "" {
+		c.report(path, "neither animal nor question")
+	}
+	if n.No == nil {
+		c.report(path, "no branch missing")
+	} else {
+		c.walk(n.No, path+"n")
+	}
+	if n.Yes == nil {
+		c.report(path, "yes branch missing")
+	} else {
+		c.walk(n.Yes, path+"y")
+	}
+}
+
+// Problems found in tree rooted at root
+func checkTree(root *node) []string {
+	c := &checker{ids: make(map[string]string), animals: make(map[string]string), seen: make(map[*node]bool)}
+	c.walk(root, "")
+	return c.problems
+}
+
+func runCheck(cmd *command, args []string) {
+	if len(args) != 1 {
+		cmd.fail("database expected")
+	}
+	d, err := loadDatabase(args[0])
+	if err != nil {
+		log.Panic("can not load db: ", err)
+	}
+	problems := checkTree(d.Root)
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "%s: %d problem(s)\n", args[0], len(problems))
+		os.Exit(1)
+	}
+}
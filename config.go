@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// User settings read from config.json in the ask-and-learn directory of
+// the user configuration directory, or from $ASK_AND_LEARN_CONFIG.  A
+// missing file means default settings.
+type config struct {
+	// Extra answers accepted as yes and no, e.g. emoji reactions
+	Yes []string `json:",omitempty"`
+	No  []string `json:",omitempty"`
+
+	// Hooks moderating taught content (see moderate.go)
+	ModerationCommand []string `json:",omitempty"`
+	ModerationURL     string   `json:",omitempty"`
+
+	// OpenAI-compatible endpoint, e.g. https://api.openai.com/v1, model
+	// and variable holding the API key (see llm.go)
+	LLMURL    string `json:",omitempty"`
+	LLMModel  string `json:",omitempty"`
+	LLMKeyEnv string `json:",omitempty"`
+
+	// Hooks notified of game events (see hooks.go)
+	HookCommand []string `json:",omitempty"`
+	HookURL     string   `json:",omitempty"`
+
+	// Community server receiving animals taught, for players who opt in
+	// (see telemetry.go)
+	TelemetryURL string `json:",omitempty"`
+
+	// Maximum number of nodes of trees games may teach to, 0 for no limit,
+	// and what to do when reached (see limit.go)
+	MaxNodes   int    `json:",omitempty"`
+	SizePolicy string `json:",omitempty"`
+
+	// Name of curator, "admin" by default, and variable holding the
+	// password of the dashboard served with the database (see admin.go)
+	AdminUser        string `json:",omitempty"`
+	AdminPasswordEnv string `json:",omitempty"`
+
+	// Variable holding the secret shared by peers allowed to push changes
+	// other than animals learned (see sync.go)
+	PeerTokenEnv string `json:",omitempty"`
+}
+
+var (
+	configOnce sync.Once
+	userConfig config
+)
+
+func settings() *config {
+	configOnce.Do(loadConfig)
+	return &userConfig
+}
+
+func configPath() string {
+	if path := os.Getenv("ASK_AND_LEARN_CONFIG"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "ask-and-learn", "config.json")
+}
+
+func loadConfig() {
+	path := configPath()
+	if path == "" {
+		return
+	}
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		log.Panic("can not read config: ", err)
+	}
+	if err := json.Unmarshal(content, &userConfig); err != nil {
+		log.Panic("can not parse config ", path, ": ", err)
+	}
+	checkSizePolicy(&userConfig)
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Lock-free reads for "ask-and-learn serve".  Writers (games played in
+// rooms, merges, curators) hold the server lock and update the working
+// database in place.  After each change, they publish an immutable snapshot
+// that readers load atomically and traverse without locking.
+//
+// Snapshots are built copy-on-write: subtrees left unchanged since the
+// previous snapshot are shared with it, so that publishing allocates only
+// the changed nodes and their ancestors.  Snapshots must never be modified.
+
+import "reflect"
+
+// Builder of snapshots of a working tree
+type freezer struct {
+	// Frozen copy of working nodes in the last snapshot
+	frozen map[*node]*node
+}
+
+// Immutable copy of working tree n sharing unchanged nodes with the last
+// snapshot
+func (f *freezer) freeze(n *node) *node {
+	next := make(map[*node]*node)
+	r := f.freezeNode(n, next)
+	f.frozen = next
+	return r
+}
+
+func (f *freezer) freezeNode(n *node, next map[*node]*node) *node {
+	if n == nil {
+		return nil
+	}
+	if c, ok := next[n]; ok {
+		// Shared subtree (see dag.go) already frozen during this pass
+		return c
+	}
+	no, yes := f.freezeNode(n.No, next), f.freezeNode(n.Yes, next)
+	if old := f.frozen[n]; old != nil && old.No == no && old.Yes == yes && sameContent(old, n) {
+		next[n] = old
+		return old
+	}
+	c := *n
+	c.No, c.Yes = no, yes
+	// Maps and slices are updated in place by writers.
+	c.Variants = append([]variant(nil), n.Variants...)
+	c.Translations = copyStrings(n.Translations)
+	c.Guess = copyStrings(n.Guess)
+	next[n] = &c
+	return &c
+}
+
+// Report whether nodes hold the same content, children aside
+func sameContent(a, b *node) bool {
+	x, y := *a, *b
+	x.No, x.Yes, y.No, y.Yes = nil, nil, nil, nil
+	if len(x.Variants) == 0 && len(y.Variants) == 0 {
+		x.Variants, y.Variants = nil, nil
+	}
+	if len(x.Translations) == 0 && len(y.Translations) == 0 {
+		x.Translations, y.Translations = nil, nil
+	}
+	if len(x.Guess) == 0 && len(y.Guess) == 0 {
+		x.Guess, y.Guess = nil, nil
+	}
+	return reflect.DeepEqual(x, y)
+}
+
+func copyStrings(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// Publish snapshot of working database.  Must be called with server lock
+// held.
+func (s *server) publish() {
+	snap := &database{
+		Category: s.db.Category,
+		Language: s.db.Language,
+		Root:     s.freezer.freeze(s.db.Root),
+		Base:     s.db.Base, // replaced, never modified, by writers
+		Clock:    s.db.Clock,
+		Ops:      append([]*op(nil), s.db.Ops...),
+	}
+	s.snapshot.Store(snap)
+}
+
+// Last published snapshot of database, to be read without locking
+func (s *server) view() *database {
+	return s.snapshot.Load().(*database)
+}
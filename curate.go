@@ -0,0 +1,314 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Commands fixing the tree by hand.  Changes are recorded as ops so that
+// they propagate to other copies (see oplog.go).
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// Replace each parent of leaf by the sibling of leaf, repointing references
+// so that shared nodes stay shared (see dag.go).  Returns false if leaf is
+// the root or not in tree.  Updates index if not nil.
+func removeLeaf(root **node, leaf *node, index map[string]*node) bool {
+	if index != nil {
+		delete(index, leaf.ID)
+	}
+	removed := false
+	for {
+		parent := parentOf(*root, leaf)
+		if parent == nil {
+			return removed
+		}
+		sibling := parent.Yes
+		if sibling == leaf {
+			sibling = parent.No
+		}
+		if sibling == leaf {
+			// Both answers lead to leaf: parent goes too.
+			if parent == *root {
+				return removed
+			}
+			leaf = parent
+		} else {
+			replaceNode(root, parent, sibling)
+			removed = true
+		}
+		if index != nil {
+			delete(index, parent.ID)
+		}
+	}
+}
+
+// Question node whose child is n, nil if none
+func parentOf(root, n *node) *node {
+	if root.isLeaf() {
+		return nil
+	}
+	if root.No == n || root.Yes == n {
+		return root
+	}
+	if p := parentOf(root.No, n); p != nil {
+		return p
+	}
+	return parentOf(root.Yes, n)
+}
+
+// Remove animal from tree
+func (d *database) remove(leaf *node) bool {
+	if parentOf(d.Root, leaf) == nil {
+		return false
+	}
+	d.record(&op{Kind: opDelete, Target: leaf.ID, Animal: leaf.Animal})
+	return removeLeaf(&d.Root, leaf, nil)
+}
+
+func init() {
+	cmd := &command{
+		Name:  "delete",
+		Args:  "database-file animal",
+		Short: "remove animal and the question leading to it",
+		Run:   runDelete,
+	}
+	deleteYes = cmd.Flag.Bool("y", false, "do not ask for confirmation")
+	commands = append(commands, cmd)
+}
+
+var deleteYes *bool
+
+// Leaf of animal named on command line, exiting if unknown
+func mustFindAnimal(d *database, path, name string) *node {
+	leaf := d.findAnimal(name)
+	if leaf == nil {
+		fmt.Fprintf(os.Stderr, "%s: unknown animal %q", path, name)
+		var similar []string
+		for _, n := range d.search(name) {
+			similar = append(similar, n.Animal)
+		}
+		if len(similar) > 0 {
+			fmt.Fprintf(os.Stderr, " (similar: %s)", strings.Join(similar, ", "))
+		}
+		fmt.Fprintln(os.Stderr)
+		os.Exit(1)
+	}
+	return leaf
+}
+
+func runDelete(cmd *command, args []string) {
+	if len(args) != 2 {
+		cmd.fail("database and animal expected")
+	}
+	d, err := loadDatabase(args[0])
+	if err != nil {
+		log.Panic("can not load db: ", err)
+	}
+	leaf := mustFindAnimal(d, args[0], args[1])
+	parent := parentOf(d.Root, leaf)
+	if parent == nil {
+		fmt.Fprintf(os.Stderr, "%s: can not delete last animal\n", args[0])
+		os.Exit(1)
+	}
+	if !*deleteYes {
+		stdin = bufio.NewReader(os.Stdin)
+		if !askYesNo("Delete %s and question %q?", leaf.Animal, parent.Question) {
+			return
+		}
+	}
+	d.remove(leaf)
+	err = d.save(args[0])
+	if err != nil {
+		log.Panic("can not save db: ", err)
+	}
+}
+
+// Give new name to animal and to its translations having the old one
+func (d *database) rename(leaf *node, old, name string) {
+	for lang, t := range leaf.Translations {
+		if sameName(t, old) {
+			leaf.Translations[lang] = name
+		}
+	}
+	if sameName(leaf.Animal, old) {
+		d.record(&op{Kind: opRename, Target: leaf.ID, Animal: name})
+		leaf.Animal = name
+	}
+}
+
+func init() {
+	commands = append(commands, &command{
+		Name:  "rename",
+		Args:  "database-file animal new-name",
+		Short: "rename animal",
+		Run:   runRename,
+	})
+}
+
+func runRename(cmd *command, args []string) {
+	if len(args) != 3 {
+		cmd.fail("database, animal and new name expected")
+	}
+	d, err := loadDatabase(args[0])
+	if err != nil {
+		log.Panic("can not load db: ", err)
+	}
+	leaf := mustFindAnimal(d, args[0], args[1])
+	name := strings.TrimSpace(args[2])
+	if other := d.findAnimal(name); other != nil && other != leaf {
+		fmt.Fprintf(os.Stderr, "%s: %s already known\n", args[0], other.Animal)
+		os.Exit(1)
+	}
+	if v, reason := moderate(name, false); v == reject {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", name, reason)
+		os.Exit(1)
+	}
+	d.rename(leaf, args[1], name)
+	err = d.save(args[0])
+	if err != nil {
+		log.Panic("can not save db: ", err)
+	}
+}
+
+// Question nodes of subtree n
+func questionNodes(n *node) []*node {
+	var found []*node
+	visit(n, func(n *node) {
+		if !n.isLeaf() {
+			found = append(found, n)
+		}
+	})
+	return found
+}
+
+// Question node reached from root by answers, a string of y and n
+func (d *database) followPath(answers string) (*node, error) {
+	n, err := d.nodeAt(answers)
+	if err == nil && n.isLeaf() {
+		return nil, fmt.Errorf("path %q reaches %s", answers, n.Animal)
+	}
+	return n, err
+}
+
+// Node, question or animal, reached from root by answers
+func (d *database) nodeAt(answers string) (*node, error) {
+	n := d.Root
+	for _, a := range answers {
+		if n.isLeaf() {
+			return nil, fmt.Errorf("path %q goes past %s", answers, n.Animal)
+		}
+		switch a {
+		case 'y':
+			n = n.Yes
+		case 'n':
+			n = n.No
+		default:
+			return nil, fmt.Errorf("path %q: y or n expected", answers)
+		}
+	}
+	return n, nil
+}
+
+// Question nodes whose question is or contains text
+func (d *database) searchQuestions(text string) []*node {
+	var exact, found []*node
+	for _, n := range questionNodes(d.Root) {
+		if sameName(n.Question, text) {
+			exact = append(exact, n)
+		} else if strings.Contains(fold(n.Question), fold(text)) {
+			found = append(found, n)
+		}
+	}
+	if len(exact) > 0 {
+		return exact
+	}
+	return found
+}
+
+// Rephrase question of n, dropping variants being tried
+func (d *database) editQuestion(n *node, question string) {
+	d.record(&op{Kind: opEdit, Target: n.ID, Question: question})
+	n.Question = question
+	n.Variants = nil
+}
+
+func init() {
+	cmd := &command{
+		Name:  "edit-question",
+		Args:  "database-file [question] new-question",
+		Short: "rephrase question found by text or by -path",
+		Run:   runEditQuestion,
+	}
+	editPath = cmd.Flag.String("path", "", "answers leading to question from the root, e.g. yny")
+	commands = append(commands, cmd)
+}
+
+var editPath *string
+
+func runEditQuestion(cmd *command, args []string) {
+	if len(args) < 2 {
+		cmd.fail("database and new question expected")
+	}
+	d, err := loadDatabase(args[0])
+	if err != nil {
+		log.Panic("can not load db: ", err)
+	}
+	var n *node
+	switch {
+	case *editPath != "":
+		if len(args) != 2 {
+			cmd.fail("either question or -path expected")
+		}
+		n, err = d.followPath(*editPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", args[0], err)
+			os.Exit(1)
+		}
+	case len(args) == 3:
+		found := d.searchQuestions(args[1])
+		if len(found) != 1 {
+			fmt.Fprintf(os.Stderr, "%s: %d questions match %q\n", args[0], len(found), args[1])
+			for _, q := range found {
+				fmt.Fprintf(os.Stderr, "    %s\n", q.Question)
+			}
+			os.Exit(1)
+		}
+		n = found[0]
+	default:
+		cmd.fail("question or -path expected")
+	}
+	question := strings.TrimSpace(args[len(args)-1])
+	if v, reason := moderate(question, true); v == reject {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", question, reason)
+		os.Exit(1)
+	}
+	d.editQuestion(n, question)
+	err = d.save(args[0])
+	if err != nil {
+		log.Panic("can not save db: ", err)
+	}
+}
@@ -0,0 +1,335 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Shared subtrees.  Hand-curated databases often ask the same chain of
+// questions in several branches, e.g. to tell apart birds whether or not they
+// live near water.  Such a chain can be stored once and referenced from every
+// branch: the tree becomes a directed acyclic graph whose shared nodes have
+// several parents.  Animals taught or questions edited below a shared node
+// apply to all branches leading to it.
+//
+// In files, the first occurrence of a shared node in depth-first order is
+// written in full and the others as {"Ref": ID}.
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+)
+
+func init() {
+	cmd := &command{
+		Name:  "dedupe",
+		Args:  "database-file",
+		Short: "share identical question chains between branches",
+		Run:   runDedupe,
+	}
+	dedupeDryRun = cmd.Flag.Bool("n", false, "report what would be shared without modifying database")
+	commands = append(commands, cmd)
+
+	cmd = &command{
+		Name:  "unshare",
+		Args:  "database-file",
+		Short: "give branch found by -path its own copy of shared subtree",
+		Run:   runUnshare,
+	}
+	unsharePath = cmd.Flag.String("path", "", "answers leading to shared node from the root, e.g. yny")
+	commands = append(commands, cmd)
+}
+
+var (
+	dedupeDryRun *bool
+	unsharePath  *string
+)
+
+// Call f once on each node of tree, even if shared, in depth-first order
+func visit(root *node, f func(*node)) {
+	seen := make(map[*node]bool)
+	var walk func(n *node)
+	walk = func(n *node) {
+		if n == nil || seen[n] {
+			return
+		}
+		seen[n] = true
+		f(n)
+		walk(n.No)
+		walk(n.Yes)
+	}
+	walk(root)
+}
+
+// Make all references to old in tree rooted at *root refer to by
+func replaceNode(root **node, old, by *node) {
+	if *root == old {
+		*root = by
+		return
+	}
+	visit(*root, func(n *node) {
+		if n.No == old {
+			n.No = by
+		}
+		if n.Yes == old {
+			n.Yes = by
+		}
+	})
+}
+
+// Nodes of tree reachable by several paths
+func sharedNodes(root *node) map[*node]bool {
+	seen := make(map[*node]bool)
+	shared := make(map[*node]bool)
+	var walk func(n *node)
+	walk = func(n *node) {
+		if n == nil {
+			return
+		}
+		if seen[n] {
+			shared[n] = true
+			return
+		}
+		seen[n] = true
+		walk(n.No)
+		walk(n.Yes)
+	}
+	walk(root)
+	return shared
+}
+
+// Deep copy of tree preserving sharing
+func cloneShared(n *node, clones map[*node]*node) *node {
+	if n == nil {
+		return nil
+	}
+	if c, ok := clones[n]; ok {
+		return c
+	}
+	c := *n
+	clones[n] = &c
+	c.No = cloneShared(n.No, clones)
+	c.Yes = cloneShared(n.Yes, clones)
+	return &c
+}
+
+// Copy of tree to serialize: shared nodes after the first occurrence are
+// replaced by references.
+func withRefs(root *node) *node {
+	written := make(map[*node]bool)
+	var write func(n *node) *node
+	write = func(n *node) *node {
+		if n == nil {
+			return nil
+		}
+		if written[n] {
+			return &node{Ref: n.ID}
+		}
+		written[n] = true
+		c := *n
+		c.No = write(n.No)
+		c.Yes = write(n.Yes)
+		return &c
+	}
+	return write(root)
+}
+
+// Replace references in tree by the nodes they designate
+func resolveRefs(root *node) error {
+	index := make(map[string]*node)
+	var collect func(n *node)
+	collect = func(n *node) {
+		if n == nil || n.Ref != "" {
+			return
+		}
+		if n.ID != "" {
+			index[n.ID] = n
+		}
+		collect(n.No)
+		collect(n.Yes)
+	}
+	collect(root)
+
+	onPath := make(map[*node]bool)
+	done := make(map[*node]bool)
+	var resolve func(child **node) error
+	resolve = func(child **node) error {
+		n := *child
+		if n == nil {
+			return nil
+		}
+		if n.Ref != "" {
+			target, ok := index[n.Ref]
+			if !ok {
+				return fmt.Errorf("reference to unknown node %q", n.Ref)
+			}
+			*child, n = target, target
+		}
+		if onPath[n] {
+			return fmt.Errorf("node %q is its own descendant", n.ID)
+		}
+		if done[n] {
+			return nil
+		}
+		onPath[n] = true
+		if err := resolve(&n.No); err != nil {
+			return err
+		}
+		if err := resolve(&n.Yes); err != nil {
+			return err
+		}
+		delete(onPath, n)
+		done[n] = true
+		return nil
+	}
+	if root != nil && root.Ref != "" {
+		return fmt.Errorf("root refers to node %q", root.Ref)
+	}
+	return resolve(&root)
+}
+
+// Share identical question subtrees of tree, merging their statistics
+func dedupe(root *node) {
+	canonical := make(map[string]*node)
+	keys := make(map[*node]string)
+	var key func(n *node) string
+	key = func(n *node) string {
+		if k, ok := keys[n]; ok {
+			return k
+		}
+		h := sha256.New()
+		if n.isLeaf() {
+			fmt.Fprintf(h, "A%q", n.Animal)
+		} else {
+			fmt.Fprintf(h, "Q%q%s%s", n.Question, key(n.No), key(n.Yes))
+		}
+		k := hex.EncodeToString(h.Sum(nil))
+		keys[n] = k
+		return k
+	}
+	// Children first so that the largest identical subtrees are shared
+	// whole.
+	var walk func(child **node)
+	walk = func(child **node) {
+		n := *child
+		if n.isLeaf() {
+			return
+		}
+		walk(&n.No)
+		walk(&n.Yes)
+		k := key(n)
+		c, ok := canonical[k]
+		switch {
+		case !ok:
+			canonical[k] = n
+		case c != n:
+			mergeStats(c, n)
+			*child = c
+		}
+	}
+	walk(&root)
+}
+
+// Add statistics of subtree from to those of identical subtree into
+func mergeStats(into, from *node) {
+	if into == nil || into == from {
+		return
+	}
+	into.NoCount += from.NoCount
+	into.YesCount += from.YesCount
+	into.ChosenCount += from.ChosenCount
+	into.GuessCount += from.GuessCount
+	into.ConfusingCount += from.ConfusingCount
+	if from.LastChosen > into.LastChosen {
+		into.LastChosen = from.LastChosen
+	}
+	mergeStats(into.No, from.No)
+	mergeStats(into.Yes, from.Yes)
+}
+
+func runDedupe(cmd *command, args []string) {
+	if len(args) != 1 {
+		cmd.fail("database expected")
+	}
+	d, err := loadDatabase(args[0])
+	if err != nil {
+		log.Panic("can not load db: ", err)
+	}
+	root := cloneTree(d.Root)
+	before := nodeCount(root)
+	dedupe(root)
+	saved := before - nodeCount(root)
+	fmt.Printf("%d subtree(s) shared, %d node(s) saved\n", len(sharedNodes(root)), saved)
+	if *dedupeDryRun || saved == 0 {
+		return
+	}
+	if !confirmRebase(d) {
+		return
+	}
+	d.Root = root
+	d.rebase()
+	err = d.save(args[0])
+	if err != nil {
+		log.Panic("can not save db: ", err)
+	}
+}
+
+func runUnshare(cmd *command, args []string) {
+	if len(args) != 1 {
+		cmd.fail("database expected")
+	}
+	if *unsharePath == "" {
+		cmd.fail("-path expected")
+	}
+	d, err := loadDatabase(args[0])
+	if err != nil {
+		log.Panic("can not load db: ", err)
+	}
+	path := *unsharePath
+	last := path[len(path)-1]
+	parent, err := d.followPath(path[:len(path)-1])
+	if err == nil && last != 'y' && last != 'n' {
+		err = fmt.Errorf("path %q: y or n expected", path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", args[0], err)
+		os.Exit(1)
+	}
+	child := &parent.No
+	if last == 'y' {
+		child = &parent.Yes
+	}
+	if !sharedNodes(d.Root)[*child] {
+		fmt.Fprintf(os.Stderr, "%s: node at path %q is not shared\n", args[0], path)
+		os.Exit(1)
+	}
+	if !confirmRebase(d) {
+		return
+	}
+	*child = cloneShared(*child, make(map[*node]*node))
+	d.rebase()
+	err = d.save(args[0])
+	if err != nil {
+		log.Panic("can not save db: ", err)
+	}
+}
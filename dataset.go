@@ -0,0 +1,302 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Conversion of public "20 questions" datasets into databases.  Two shapes
+// are understood:
+//
+//   - attribute lists: CSV files as read by loadAttributes, e.g. one column
+//     per feature, from which a balanced tree is built (see optimize.go);
+//   - nested questions: JSON objects holding a question and yes and no
+//     branches, leaves being names or objects holding an animal, under
+//     common key names.
+//
+// Texts are cleaned up: white space is collapsed and questions get a
+// capital letter and a question mark.  A mapping file renames questions,
+// feature columns and animals, or drops them when mapped to nothing.
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+func init() {
+	cmd := &command{
+		Name:  "import-dataset",
+		Args:  "database-file dataset-file",
+		Short: "create database from CSV attribute list or nested JSON questions",
+		Run:   runImportDataset,
+	}
+	datasetFormat = cmd.Flag.String("format", "", "csv or json (default: from file extension)")
+	datasetMapping = cmd.Flag.String("map", "", "CSV file of \"from,to\" renamings, an empty \"to\" dropping the text")
+	datasetCategory = cmd.Flag.String("category", defaultCategory, "kind of things to guess")
+	datasetForce = cmd.Flag.Bool("f", false, "overwrite existing file")
+	commands = append(commands, cmd)
+}
+
+var (
+	datasetFormat   *string
+	datasetMapping  *string
+	datasetCategory *string
+	datasetForce    *bool
+)
+
+// Renamings of texts of dataset, "" dropping text
+type mapping map[string]string
+
+func loadMapping(path string) (mapping, error) {
+	m := make(mapping)
+	if path == "" {
+		return m, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.Comment = '#'
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return m, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+		to := ""
+		if len(record) > 1 {
+			to = record[1]
+		}
+		m[cleanText(record[0])] = cleanText(to)
+	}
+}
+
+// Mapped text, "" if dropped
+func (m mapping) apply(s string) string {
+	s = cleanText(s)
+	if to, ok := m[s]; ok {
+		return to
+	}
+	return s
+}
+
+func cleanText(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Question with a capital letter and a question mark
+func cleanQuestion(q string) string {
+	if q == "" {
+		return ""
+	}
+	r, size := utf8.DecodeRuneInString(q)
+	q = string(unicode.ToUpper(r)) + q[size:]
+	if !strings.HasSuffix(q, "?") {
+		q += "?"
+	}
+	return q
+}
+
+// Tree distinguishing animals of attribute table
+func treeFromAttributes(attrs *attributeTable, m mapping) (*node, error) {
+	o := new(optimizer)
+	renamed := make(map[string]string)
+	for _, q := range attrs.questions {
+		if to := cleanQuestion(m.apply(q)); to != "" {
+			renamed[q] = to
+			o.questions = append(o.questions, to)
+		}
+	}
+	byName := make(map[string]*animalFacts)
+	for _, animal := range attrs.animals {
+		name := m.apply(animal)
+		if name == "" {
+			continue
+		}
+		c := byName[fold(name)]
+		if c == nil {
+			c = &animalFacts{leaf: &node{Animal: name}, weight: 1, answers: make(map[string]bool), conflict: make(map[string]bool)}
+			byName[fold(name)] = c
+			o.animals = append(o.animals, c)
+		}
+		for q, yes := range attrs.answers[animal] {
+			if to := renamed[q]; to != "" {
+				c.learn(to, yes)
+			}
+		}
+	}
+	if len(o.animals) == 0 {
+		return nil, fmt.Errorf("no animal")
+	}
+	return o.build(o.animals)
+}
+
+// Keys of nested datasets
+var (
+	questionKeys = []string{"question", "q", "text"}
+	yesKeys      = []string{"yes", "y"}
+	noKeys       = []string{"no", "n"}
+	animalKeys   = []string{"animal", "answer", "guess", "name", "object"}
+)
+
+// Value of first key of object present, ignoring case
+func lookupKey(obj map[string]interface{}, keys []string) (interface{}, bool) {
+	names := make([]string, 0, len(obj))
+	for k := range obj {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, key := range keys {
+		if v, ok := obj[key]; ok {
+			return v, true
+		}
+		for _, k := range names {
+			if strings.EqualFold(k, key) {
+				return obj[k], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Tree of nested dataset value v, nil if empty
+func treeFromNested(v interface{}, m mapping) (*node, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return &node{Animal: m.apply(v)}, nil
+	case map[string]interface{}:
+		no, hasNo := lookupKey(v, noKeys)
+		yes, hasYes := lookupKey(v, yesKeys)
+		if !hasNo && !hasYes {
+			// Leaf, whatever key names it, e.g. text
+			if a, ok := lookupKey(v, animalKeys); ok {
+				return treeFromNested(a, m)
+			}
+			if a, ok := lookupKey(v, questionKeys); ok {
+				return treeFromNested(a, m)
+			}
+			break
+		}
+		if q, ok := lookupKey(v, questionKeys); ok {
+			question, ok := q.(string)
+			if !ok {
+				return nil, fmt.Errorf("question expected, got %v", q)
+			}
+			n := &node{Question: cleanQuestion(m.apply(question))}
+			var err error
+			if n.No, err = treeFromNested(no, m); err != nil {
+				return nil, err
+			}
+			if n.Yes, err = treeFromNested(yes, m); err != nil {
+				return nil, err
+			}
+			return n, nil
+		}
+	}
+	return nil, fmt.Errorf("question or animal expected, got %v", v)
+}
+
+func runImportDataset(cmd *command, args []string) {
+	if len(args) != 2 {
+		cmd.fail("database and dataset expected")
+	}
+	if _, err := os.Stat(args[0]); err == nil && !*datasetForce {
+		fmt.Fprintf(os.Stderr, "%s already exists (use -f to overwrite)\n", args[0])
+		os.Exit(1)
+	}
+	if _, ok := categories[*datasetCategory]; !ok {
+		cmd.fail("unknown category %q", *datasetCategory)
+	}
+	m, err := loadMapping(*datasetMapping)
+	if err != nil {
+		log.Panic("can not load mapping: ", err)
+	}
+
+	format := *datasetFormat
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(args[1])), ".")
+	}
+	var root *node
+	switch format {
+	case "csv":
+		attrs, err := loadAttributes(args[1])
+		if err != nil {
+			log.Panic("can not load dataset: ", err)
+		}
+		root, err = treeFromAttributes(attrs, m)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", args[1], err)
+			os.Exit(1)
+		}
+	case "json":
+		content, err := ioutil.ReadFile(args[1])
+		if err != nil {
+			log.Panic("can not load dataset: ", err)
+		}
+		var v interface{}
+		if err := json.Unmarshal(content, &v); err != nil {
+			log.Panic("can not parse dataset: ", err)
+		}
+		root, err = treeFromNested(v, m)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", args[1], err)
+			os.Exit(1)
+		}
+	default:
+		cmd.fail("unknown format %q: use -format", format)
+	}
+
+	// Drop what the mapping emptied and branches leading to the same animal.
+	root, _ = newPruner(root).prune(root)
+	if root == nil {
+		fmt.Fprintf(os.Stderr, "%s: no animal\n", args[1])
+		os.Exit(1)
+	}
+	if err := moderateTree(root); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", args[1], err)
+		os.Exit(1)
+	}
+	d := newDatabase(root)
+	if *datasetCategory != defaultCategory {
+		d.Category = *datasetCategory
+	}
+	fmt.Printf("%d animals, %d questions\n", root.leafCount(), len(questionNodes(root)))
+	err = d.save(args[0])
+	if err != nil {
+		log.Panic("can not save db: ", err)
+	}
+}
@@ -0,0 +1,170 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+)
+
+// On-disk knowledge base: the current tree plus the history needed to merge
+// it with other copies.
+type database struct {
+	// Kind of things stored in leaves (see category.go)
+	Category string `json:",omitempty"`
+
+	// Language of questions and animals, translations aside
+	Language string `json:",omitempty"`
+
+	// Current knowledge tree
+	Root *node
+
+	// Op-log (see oplog.go): Root is Base with Ops applied in order.
+	Base  *node
+	Clock uint64
+	Ops   []*op `json:",omitempty"`
+
+	// Player statistics by name (see profile.go)
+	Profiles map[string]*profile `json:",omitempty"`
+
+	// Games paused by player name, empty for anonymous players (see
+	// resume.go)
+	Paused map[string]*pausedGame `json:",omitempty"`
+
+	// IDs of ops kept in the personal overlay of the player (see
+	// overlay.go), nil if none
+	personal map[string]bool
+}
+
+// Create database whose initial content is a copy of tree
+func newDatabase(tree *node) *database {
+	root := cloneTree(tree)
+	assignIDs(root, "r")
+	return &database{Root: root, Base: cloneTree(root)}
+}
+
+// Read database from file.  Files holding a bare tree, as written by older
+// versions, are accepted and start an empty op-log.
+func loadDatabase(path string) (*database, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseDatabase(content)
+}
+
+func parseDatabase(content []byte) (*database, error) {
+	d := new(database)
+	err := json.Unmarshal(content, d)
+	if err != nil {
+		return nil, err
+	}
+	if d.Root == nil {
+		root := new(node)
+		err = json.Unmarshal(content, root)
+		if err != nil {
+			return nil, err
+		}
+		if err = resolveRefs(root); err != nil {
+			return nil, err
+		}
+		return newDatabase(root), nil
+	}
+	if err = resolveRefs(d.Root); err != nil {
+		return nil, err
+	}
+	if err = resolveRefs(d.Base); err != nil {
+		return nil, err
+	}
+	if d.Base == nil {
+		assignIDs(d.Root, "r")
+		d.Base = cloneTree(d.Root)
+	}
+	return d, nil
+}
+
+// Write database to file
+func (d *database) save(path string) error {
+	saved := *d
+	saved.Root, saved.Base = withRefs(d.Root), withRefs(d.Base)
+	content, err := json.MarshalIndent(&saved, "", "    ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, content, 0700)
+}
+
+// Deep copy of tree, shared subtrees included (see dag.go)
+func cloneTree(n *node) *node {
+	return cloneShared(n, make(map[*node]*node))
+}
+
+// Give nodes lacking one an ID derived from their position so that copies of
+// the same tree agree on IDs.
+func assignIDs(n *node, id string) {
+	if n == nil {
+		return
+	}
+	if n.ID == "" {
+		n.ID = id
+	}
+	assignIDs(n.No, n.ID+"n")
+	assignIDs(n.Yes, n.ID+"y")
+}
+
+// Leaves of tree in depth-first order, no before yes
+func leaves(n *node) []*node {
+	var found []*node
+	visit(n, func(n *node) {
+		if n.isLeaf() {
+			found = append(found, n)
+		}
+	})
+	return found
+}
+
+// Leaf of tree naming animal if any
+func (d *database) findAnimal(animal string) *node {
+	for _, leaf := range leaves(d.Root) {
+		if sameName(leaf.Animal, animal) || sameName(leaf.localized(), animal) {
+			return leaf
+		}
+	}
+	return nil
+}
+
+// Number of leaves of subtree
+func (n *node) leafCount() int {
+	if n.isLeaf() {
+		return 1
+	}
+	return n.No.leafCount() + n.Yes.leafCount()
+}
+
+// Report whether n belongs to subtree root
+func (n *node) isDescendantOf(root *node) bool {
+	if root == n {
+		return true
+	}
+	return !root.isLeaf() && (n.isDescendantOf(root.No) || n.isDescendantOf(root.Yes))
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Flag values implied by each difficulty level.  Flags set explicitly on the
+// command line take precedence.
+var difficulties = map[string]map[string]string{
+	"easy": {
+		"early-size":    "4",
+		"guesses":       "5",
+		"popular":       "true",
+		"hints":         "true",
+		"max-questions": "15",
+	},
+	"normal": {},
+	"hard": {
+		"early-size":    "0",
+		"popular":       "false",
+		"hints":         "false",
+		"max-questions": "20",
+	},
+}
+
+func difficultyNames() string {
+	var names []string
+	for name := range difficulties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+var difficulty = flag.String("difficulty", "normal", "game settings preset: "+difficultyNames())
+
+// Set flags according to chosen difficulty
+func applyDifficulty() {
+	preset, ok := difficulties[*difficulty]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown difficulty %q\n", *difficulty)
+		usage()
+		os.Exit(1)
+	}
+	explicit := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	for name, value := range preset {
+		if !explicit[name] {
+			flag.Set(name, value)
+		}
+	}
+}
@@ -0,0 +1,380 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Interactive curation of a database
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	commands = append(commands, &command{
+		Name:  "edit",
+		Args:  "database-file",
+		Short: "navigate tree and fix it interactively",
+		Run:   runEdit,
+	})
+}
+
+// State of edit session
+type editor struct {
+	d     *database
+	path  string
+	nodes []*node // from root to current node
+	dirty bool    // whether there are unsaved changes
+
+	// Changes that can be undone (see undo.go)
+	history history
+}
+
+const editHelp = `y, n          follow yes or no branch
+up, top       go to parent or root
+show [depth]  print subtree
+find ANIMAL   go to animal
+rename TEXT   rename animal or rephrase question
+delete        delete animal
+move PATH     move animal under node reached by PATH, e.g. yny
+graft PATH    move question and its subtree under node reached by PATH
+unshare       give current branch its own copy of shared subtree
+undo, redo    undo or redo last change
+save          save database
+quit          leave, asking to save changes`
+
+func runEdit(cmd *command, args []string) {
+	if len(args) != 1 {
+		cmd.fail("database expected")
+	}
+	d, err := loadDatabase(args[0])
+	if err != nil {
+		log.Panic("can not load db: ", err)
+	}
+	stdin = bufio.NewReader(os.Stdin)
+	e := &editor{d: d, path: args[0], nodes: []*node{d.Root}}
+	atEndOfInput = func() {
+		if e.dirty {
+			e.save()
+		}
+	}
+	e.recoverJournal()
+	fmt.Println(`Type "help" for commands.`)
+	for {
+		e.showCurrent()
+		fields := strings.Fields(ask("edit>"))
+		if len(fields) == 0 {
+			continue
+		}
+		arg := strings.Join(fields[1:], " ")
+		if !e.run(fields[0], arg) {
+			return
+		}
+	}
+}
+
+func (e *editor) current() *node {
+	return e.nodes[len(e.nodes)-1]
+}
+
+func (e *editor) showCurrent() {
+	n := e.current()
+	if n.isLeaf() {
+		fmt.Printf("[%s] %s\n", e.answers(), n.Animal)
+	} else {
+		fmt.Printf("[%s] %s (%d animals)\n", e.answers(), n.Question, n.leafCount())
+	}
+	if e.sharedAbove() > 0 {
+		fmt.Println("shared with other branches, changes apply to all of them")
+	}
+}
+
+// Index in e.nodes of innermost node shared between branches (see dag.go)
+// on the path to the current node, 0 if none
+func (e *editor) sharedAbove() int {
+	shared := sharedNodes(e.d.Root)
+	for i := len(e.nodes) - 1; i > 0; i-- {
+		if shared[e.nodes[i]] {
+			return i
+		}
+	}
+	return 0
+}
+
+// Replace innermost shared node above current one by a copy private to the
+// current branch
+func (e *editor) unshare() {
+	i := e.sharedAbove()
+	if i == 0 {
+		fmt.Println("not shared")
+		return
+	}
+	if !e.confirmRebase() {
+		return
+	}
+	clones := make(map[*node]*node)
+	parent, c := e.nodes[i-1], cloneShared(e.nodes[i], clones)
+	if parent.Yes == e.nodes[i] {
+		parent.Yes = c
+	} else {
+		parent.No = c
+	}
+	for ; i < len(e.nodes); i++ {
+		e.nodes[i] = clones[e.nodes[i]]
+	}
+	e.d.rebase()
+	e.rebased()
+}
+
+// Answers leading to current node as a string of y and n
+func (e *editor) answers() string {
+	var b strings.Builder
+	for i := 1; i < len(e.nodes); i++ {
+		if e.nodes[i-1].Yes == e.nodes[i] {
+			b.WriteByte('y')
+		} else {
+			b.WriteByte('n')
+		}
+	}
+	return b.String()
+}
+
+// Go to n, which must be in tree
+func (e *editor) jump(n *node) {
+	e.nodes = []*node{e.d.Root}
+	for _, s := range pathTo(e.d.Root, n) {
+		if s.yes {
+			e.nodes = append(e.nodes, e.current().Yes)
+		} else {
+			e.nodes = append(e.nodes, e.current().No)
+		}
+	}
+}
+
+// Execute command.  Returns false when leaving.
+func (e *editor) run(name, arg string) bool {
+	n := e.current()
+	switch name {
+	case "help", "?":
+		fmt.Println(editHelp)
+	case "y", "yes", "n", "no":
+		if n.isLeaf() {
+			fmt.Println("already at an animal")
+		} else if name[0] == 'y' {
+			e.nodes = append(e.nodes, n.Yes)
+		} else {
+			e.nodes = append(e.nodes, n.No)
+		}
+	case "up", "u":
+		if len(e.nodes) > 1 {
+			e.nodes = e.nodes[:len(e.nodes)-1]
+		}
+	case "top":
+		e.nodes = e.nodes[:1]
+	case "show":
+		depth := 3
+		if arg != "" {
+			var err error
+			if depth, err = strconv.Atoi(arg); err != nil {
+				fmt.Println("depth expected")
+				break
+			}
+		}
+		printTree(n, "", depth)
+	case "find":
+		found := e.d.search(arg)
+		if len(found) == 0 {
+			fmt.Printf("no animal like %q\n", arg)
+			break
+		}
+		e.jump(found[0])
+	case "rename":
+		e.rename(n, arg)
+	case "delete":
+		c := newRemoval(e.d, n)
+		if !n.isLeaf() {
+			fmt.Println("only animals can be deleted")
+		} else if c == nil {
+			fmt.Println("can not delete last animal")
+		} else if answers := e.answers(); askYesNo("Delete %s?", n.Animal) && e.d.remove(n) {
+			e.changed(c)
+			e.follow(answers[:len(answers)-1])
+		}
+	case "move":
+		e.move(n, arg)
+	case "graft":
+		e.graft(arg)
+	case "unshare":
+		e.unshare()
+	case "undo":
+		e.undo(e.history.back)
+	case "redo":
+		e.undo(e.history.forward)
+	case "save":
+		e.save()
+	case "quit", "q":
+		if e.dirty && askYesNo("Save changes?") {
+			e.save()
+		}
+		e.removeJournal()
+		return false
+	default:
+		fmt.Printf("unknown command %q, try help\n", name)
+	}
+	return true
+}
+
+func (e *editor) rename(n *node, text string) {
+	if text == "" {
+		fmt.Println("new text expected")
+		return
+	}
+	if v, reason := moderate(text, !n.isLeaf()); v == reject {
+		fmt.Println(reason)
+		return
+	}
+	c := &renaming{id: n.ID, from: n.text(), to: text}
+	if !n.isLeaf() {
+		e.d.editQuestion(n, text)
+	} else if other := e.d.findAnimal(text); other != nil && other != n {
+		fmt.Printf("%s already known\n", other.Animal)
+		return
+	} else {
+		e.d.rename(n, n.Animal, text)
+	}
+	e.changed(c)
+}
+
+// Reinsert animal leaf under the node reached by answers, asking how to
+// distinguish it there
+func (e *editor) move(leaf *node, answers string) {
+	if !leaf.isLeaf() {
+		fmt.Println("only animals can be moved")
+		return
+	}
+	// Resolve path in the tree the user sees, before removing collapses a
+	// level.
+	target, err := e.d.nodeAt(answers)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if target == leaf {
+		fmt.Println("can not move animal next to itself")
+		return
+	}
+	removed := newRemoval(e.d, leaf)
+	if removed == nil {
+		fmt.Println("can not move last animal")
+		return
+	}
+	parent := parentOf(e.d.Root, leaf)
+	e.d.remove(leaf)
+	if target == parent {
+		// Removing replaced the parent by the sibling.
+		target = e.d.nodeByID(removed.siblingID)
+	}
+	p := e.d.phrasing()
+	question := ask(p.distinguish, e.d.named(leaf.Animal), e.d.describeSubtree(target))
+	isYes := askYesNo(p.expected, e.d.named(leaf.Animal))
+	moved := &node{Animal: leaf.Animal, ChosenCount: leaf.ChosenCount, Translations: leaf.Translations,
+		Guess: leaf.Guess, Description: leaf.Description, ImageURL: leaf.ImageURL}
+	e.d.learn(target, moved, question, isYes)
+	e.changed(changes{removed, inverse{newRemoval(e.d, moved)}})
+	e.jump(moved)
+}
+
+// Reinsert current question and its subtree under the node reached by
+// answers, asking how to distinguish it there.  The op-log can not express
+// this so the database is rebased.
+func (e *editor) graft(answers string) {
+	n := e.current()
+	if n.isLeaf() {
+		fmt.Println("only questions can be grafted, use move for animals")
+		return
+	}
+	if len(e.nodes) == 1 {
+		fmt.Println("can not graft root")
+		return
+	}
+	// Resolve path in the tree the user sees, before detaching collapses a
+	// level.
+	target, err := e.d.nodeAt(answers)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if target.isDescendantOf(n) {
+		fmt.Println("can not graft question into its own subtree")
+		return
+	}
+	if !e.confirmRebase() {
+		return
+	}
+	parent := e.nodes[len(e.nodes)-2]
+	sibling := parent.Yes
+	if sibling == n {
+		sibling = parent.No
+	}
+	replaceNode(&e.d.Root, parent, sibling)
+	if target == parent {
+		// Detaching replaced the parent by the sibling.
+		target = sibling
+	}
+	p := e.d.phrasing()
+	question := ask(p.distinguish, e.d.describeSubtree(n), e.d.describeSubtree(target))
+	isYes := askYesNo(p.expected, e.d.describeSubtree(n))
+	mutateIntoQuestionNode(target, question, n, isYes)
+	e.d.rebase()
+	e.rebased()
+	e.jump(n)
+}
+
+func (e *editor) save() {
+	err := e.d.save(e.path)
+	if err != nil {
+		log.Panic("can not save db: ", err)
+	}
+	e.dirty = false
+	e.removeJournal()
+}
+
+// Print subtree n down to depth, indenting with prefix
+func printTree(n *node, prefix string, depth int) {
+	if n.isLeaf() {
+		fmt.Printf("%s%s\n", prefix, n.Animal)
+		return
+	}
+	fmt.Printf("%s%s (%d)\n", prefix, n.Question, n.leafCount())
+	if depth <= 0 {
+		fmt.Printf("%s    ...\n", prefix)
+		return
+	}
+	fmt.Printf("%s  no:\n", prefix)
+	printTree(n.No, prefix+"    ", depth-1)
+	fmt.Printf("%s  yes:\n", prefix)
+	printTree(n.Yes, prefix+"    ", depth-1)
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+)
+
+func init() {
+	commands = append(commands, &command{
+		Name:  "eval",
+		Args:  "database-file attributes-file",
+		Short: "play automatically with animals of a CSV file and report accuracy",
+		Run:   runEval,
+	})
+}
+
+// Descend from n answering questions with answer until reaching a leaf or a
+// question answer can not answer.  Returns last node and number of questions
+// answered.
+func walkTree(n *node, answer func(question string) (yes, ok bool)) (*node, int) {
+	depth := 0
+	for !n.isLeaf() {
+		yes, ok := answer(n.Question)
+		if !ok {
+			break
+		}
+		if yes {
+			n = n.Yes
+		} else {
+			n = n.No
+		}
+		depth++
+	}
+	return n, depth
+}
+
+func runEval(cmd *command, args []string) {
+	if len(args) != 2 {
+		cmd.fail("database and attributes expected")
+	}
+	d, err := loadDatabase(args[0])
+	if err != nil {
+		log.Panic("can not load db: ", err)
+	}
+	attrs, err := loadAttributes(args[1])
+	if err != nil {
+		log.Panic("can not load attributes: ", err)
+	}
+	if len(attrs.animals) == 0 {
+		fmt.Fprintf(os.Stderr, "%s: no animal\n", args[1])
+		os.Exit(1)
+	}
+
+	known := make(map[string]bool)
+	for _, leaf := range leaves(d.Root) {
+		known[leaf.Animal] = true
+	}
+
+	found, totalDepth := 0, 0
+	var missing, wrong, stuck []string
+	for _, animal := range attrs.animals {
+		n, depth := walkTree(d.Root, func(q string) (bool, bool) { return attrs.answer(animal, q) })
+		switch {
+		case !known[animal]:
+			missing = append(missing, animal)
+		case !n.isLeaf():
+			stuck = append(stuck, fmt.Sprintf("%s: no answer to %q", animal, n.Question))
+		case n.Animal != animal:
+			wrong = append(wrong, fmt.Sprintf("%s: guessed %s", animal, n.Animal))
+		default:
+			found++
+			totalDepth += depth
+		}
+	}
+
+	total := len(attrs.animals)
+	fmt.Printf("accuracy: %d/%d (%.1f%%)\n", found, total, 100*float64(found)/float64(total))
+	if found > 0 {
+		fmt.Printf("average depth: %.2f\n", float64(totalDepth)/float64(found))
+	}
+	report("not in tree", missing)
+	report("unanswerable question", stuck)
+	report("wrong guess", wrong)
+}
+
+func report(title string, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Printf("%s (%d):\n", title, len(lines))
+	for _, l := range lines {
+		fmt.Printf("    %s\n", l)
+	}
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Descriptions of animals, told as fun facts when the program finds them
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+var factsFlag = flag.Bool("facts", false, "ask players for a fun fact about animals they teach")
+
+// Tell what is known about animal found by the program
+func (g *game) funFact() {
+	if g.found && g.answer.Description != "" {
+		g.ui.tell(fmt.Sprintf(tr("Did you know? %s"), g.answer.Description))
+	}
+}
+
+// Let player describe leaf just taught
+func (g *game) askFact(leaf *node) {
+	if leaf.Description != "" || !g.ui.askYesNo(fmt.Sprintf(tr("Do you know a fun fact about %s?"), g.db.named(leaf.localized()))) {
+		return
+	}
+	leaf.Description = g.askContent(tr("Tell me:"), false)
+}
+
+func init() {
+	commands = append(commands, &command{
+		Name:  "describe",
+		Args:  "database-file animal [description]",
+		Short: "print or set description of animal, \"-\" clearing it",
+		Run:   runDescribe,
+	})
+}
+
+func runDescribe(cmd *command, args []string) {
+	if len(args) < 2 {
+		cmd.fail("database and animal expected")
+	}
+	d, err := loadDatabase(args[0])
+	if err != nil {
+		log.Panic("can not load db: ", err)
+	}
+	leaf := d.findAnimal(args[1])
+	if leaf == nil {
+		fmt.Fprintf(os.Stderr, "%s: unknown animal %q\n", args[0], args[1])
+		os.Exit(1)
+	}
+	if len(args) == 2 {
+		fmt.Println(leaf.Description)
+		return
+	}
+	leaf.Description = strings.Join(args[2:], " ")
+	if leaf.Description == "-" {
+		leaf.Description = ""
+	}
+	err = d.save(args[0])
+	if err != nil {
+		log.Panic("can not save db: ", err)
+	}
+}
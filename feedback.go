@@ -0,0 +1,90 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Players flagging confusing questions so that curators rewrite them
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+)
+
+var feedbackFlag = flag.Bool("feedback", false, "ask after each game whether a question was confusing")
+
+// Let player flag one of the questions answered in this game
+func (g *game) askFeedback() {
+	if !*feedbackFlag || len(g.asked) == 0 || !g.ui.askYesNo(tr("Were any questions confusing?")) {
+		return
+	}
+	for i, s := range g.path {
+		g.ui.tell(fmt.Sprintf("%d) %s", i+1, s.question))
+	}
+	// Answers can not be empty, whether typed or voted in rooms.
+	for {
+		i, err := strconv.Atoi(g.ui.ask(tr("Which one? (0 for none)")))
+		if err == nil && i >= 1 && i <= len(g.asked) {
+			g.asked[i-1].ConfusingCount++
+			return
+		}
+		if err == nil && i == 0 {
+			return
+		}
+	}
+}
+
+func init() {
+	commands = append(commands, &command{
+		Name:  "review-questions",
+		Args:  "database-file",
+		Short: "list questions players found confusing, most flagged first",
+		Run:   runReviewQuestions,
+	})
+}
+
+func runReviewQuestions(cmd *command, args []string) {
+	if len(args) != 1 {
+		cmd.fail("database expected")
+	}
+	d, err := loadDatabase(args[0])
+	if err != nil {
+		log.Panic("can not load db: ", err)
+	}
+	var flagged []*node
+	for _, n := range questionNodes(d.Root) {
+		if n.ConfusingCount > 0 {
+			flagged = append(flagged, n)
+		}
+	}
+	if len(flagged) == 0 {
+		fmt.Fprintf(os.Stderr, "%s: no confusing question\n", args[0])
+		return
+	}
+	sort.SliceStable(flagged, func(i, j int) bool { return flagged[i].ConfusingCount > flagged[j].ConfusingCount })
+	for _, n := range flagged {
+		fmt.Printf("%d\t%s\t%s\n", n.ConfusingCount, pathString(pathTo(d.Root, n)), n.Question)
+	}
+}
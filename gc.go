@@ -0,0 +1,170 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+)
+
+func init() {
+	cmd := &command{
+		Name:  "gc",
+		Args:  "database-file",
+		Short: "remove unreachable and degenerate nodes",
+		Run:   runGC,
+	}
+	gcDryRun = cmd.Flag.Bool("n", false, "report what would be removed without modifying database")
+	commands = append(commands, cmd)
+}
+
+var gcDryRun *bool
+
+// Number of distinct nodes of tree
+func nodeCount(n *node) int {
+	count := 0
+	visit(n, func(*node) { count++ })
+	return count
+}
+
+type pruner struct {
+	// Answers leading to the node being pruned by folded question: the
+	// other branch of a question asked again can not be reached.
+	answered map[string]bool
+
+	// Nodes reachable by several paths (see dag.go) and what they were
+	// simplified into.  Which answers lead to them depends on the path so
+	// they are simplified ignoring answers given above them.
+	shared map[*node]bool
+	done   map[*node]*node
+}
+
+func newPruner(root *node) *pruner {
+	return &pruner{
+		answered: make(map[string]bool),
+		shared:   sharedNodes(root),
+		done:     make(map[*node]*node),
+	}
+}
+
+// Simplified subtree n, nil if nothing is left, and number of nodes
+// removed
+func (p *pruner) prune(n *node) (*node, int) {
+	if n == nil {
+		return nil, 0
+	}
+	if !p.shared[n] {
+		return p.pruneNode(n)
+	}
+	if r, ok := p.done[n]; ok {
+		return r, 0
+	}
+	answered := p.answered
+	p.answered = make(map[string]bool)
+	r, removed := p.pruneNode(n)
+	p.answered = answered
+	p.done[n] = r
+	return r, removed
+}
+
+func (p *pruner) pruneNode(n *node) (*node, int) {
+	answered := p.answered
+	if n.isLeaf() {
+		removed := nodeCount(n.No) + nodeCount(n.Yes)
+		n.Question, n.No, n.Yes = "", nil, nil
+		return n, removed
+	}
+	q := fold(n.Question)
+	if yes, ok := answered[q]; ok {
+		if yes {
+			return p.pruneReplacing(n, n.Yes, n.No)
+		}
+		return p.pruneReplacing(n, n.No, n.Yes)
+	}
+	if q == "" {
+		return p.pruneReplacing(n, n.No, n.Yes)
+	}
+
+	answered[q] = false
+	no, removedNo := p.prune(n.No)
+	answered[q] = true
+	yes, removedYes := p.prune(n.Yes)
+	delete(answered, q)
+	n.No, n.Yes = no, yes
+	removed := removedNo + removedYes
+	switch {
+	case no == nil && yes == nil:
+		return nil, removed + 1
+	case no == nil:
+		return yes, removed + 1
+	case yes == nil:
+		return no, removed + 1
+	case no.isLeaf() && yes.isLeaf() && sameName(no.Animal, yes.Animal):
+		// Both answers lead to the same animal.
+		yes.ChosenCount += no.ChosenCount
+		if no.LastChosen > yes.LastChosen {
+			yes.LastChosen = no.LastChosen
+		}
+		return yes, removed + 2
+	}
+	return n, removed
+}
+
+// Replace n by kept subtree, dropping n and the other subtree
+func (p *pruner) pruneReplacing(n, kept, dropped *node) (*node, int) {
+	if kept == nil {
+		kept, dropped = dropped, nil
+	}
+	k, removed := p.prune(kept)
+	return k, removed + 1 + nodeCount(dropped)
+}
+
+func runGC(cmd *command, args []string) {
+	if len(args) != 1 {
+		cmd.fail("database expected")
+	}
+	d, err := loadDatabase(args[0])
+	if err != nil {
+		log.Panic("can not load db: ", err)
+	}
+	root := cloneTree(d.Root)
+	root, removed := newPruner(root).prune(root)
+	if root == nil {
+		fmt.Fprintf(os.Stderr, "%s: no animal\n", args[0])
+		os.Exit(1)
+	}
+	fmt.Printf("%d node(s) removed\n", removed)
+	if *gcDryRun || removed == 0 {
+		return
+	}
+	if !confirmRebase(d) {
+		return
+	}
+	d.Root = root
+	d.rebase()
+	err = d.save(args[0])
+	if err != nil {
+		log.Panic("can not save db: ", err)
+	}
+}
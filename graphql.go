@@ -0,0 +1,978 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// GraphQL API served by "ask-and-learn serve" on /graphql for custom user
+// interfaces.  Queries come as GET /graphql?query=Q or as POST of a JSON
+// {"query", "variables", "operationName"} object, mutations only as the
+// latter.  GET /graphql/schema returns the schema below.
+//
+// The executor supports the subset of GraphQL front ends commonly use:
+// queries and mutations with variables, aliases, arguments and nested
+// selections.  Fragments, directives and introspection are not supported.
+// Mutations other than teach require the credentials of the curator (see
+// admin.go).  Queries deeper than gqlMaxDepth or resolving more than
+// gqlMaxObjects objects are rejected.
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const graphQLSchema = `type Query {
+    category: String!
+    language: String
+    root: Node!
+    node(id: ID!): Node
+    path(answers: String!): Node
+    animal(name: String!): Node
+    search(name: String!): [Node!]!
+    animals: [Node!]!
+    questions: [Node!]!
+    stats: Stats!
+}
+
+type Mutation {
+    teach(id: ID!, animal: String!, question: String!, yes: Boolean!): Node
+    renameAnimal(id: ID!, name: String!): Node
+    editQuestion(id: ID!, question: String!): Node
+    deleteAnimal(id: ID!): Boolean!
+}
+
+type Node {
+    id: ID!
+    text: String!
+    question: String
+    animal: String
+    isAnimal: Boolean!
+    path: String!
+    yes: Node
+    no: Node
+    parent: Node
+    animals: [Node!]!
+    leafCount: Int!
+    yesCount: Int!
+    noCount: Int!
+    chosenCount: Int!
+    guessCount: Int!
+    confusingCount: Int!
+    description: String
+    imageURL: String
+    flagged: String
+    shared: Boolean!
+}
+
+type Stats {
+    games: Int!
+    animals: Int!
+    questions: Int!
+    nodes: Int!
+    minDepth: Int!
+    maxDepth: Int!
+    averageDepth: Float!
+}
+`
+
+// Parsed document
+
+type gqlOperation struct {
+	kind       string // query or mutation
+	name       string
+	defaults   map[string]interface{} // default values of variables
+	selections []*gqlField
+}
+
+type gqlField struct {
+	alias, name string
+	args        map[string]interface{} // variables are gqlVariable
+	selections  []*gqlField
+}
+
+type gqlVariable string
+
+type gqlParser struct {
+	src string
+	pos int
+	tok string // current token, "" at end
+	str bool   // whether tok is a string literal, unquoted
+}
+
+func parseGraphQL(src string) (ops []*gqlOperation, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(gqlError)
+			if !ok {
+				panic(r)
+			}
+			err = e
+		}
+	}()
+	p := &gqlParser{src: src}
+	p.next()
+	for p.tok != "" || p.str {
+		ops = append(ops, p.operation())
+	}
+	if len(ops) == 0 {
+		p.fail("empty document")
+	}
+	return ops, nil
+}
+
+type gqlError string
+
+func (e gqlError) Error() string { return string(e) }
+
+func (p *gqlParser) fail(format string, args ...interface{}) {
+	panic(gqlError(fmt.Sprintf(format, args...)))
+}
+
+// Move to next token, skipping white space, commas and comments
+func (p *gqlParser) next() {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == '#' {
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		} else if c == ',' || c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+			p.pos++
+		} else {
+			break
+		}
+	}
+	p.tok, p.str = "", false
+	if p.pos >= len(p.src) {
+		return
+	}
+	start := p.pos
+	c := p.src[p.pos]
+	switch {
+	case strings.HasPrefix(p.src[p.pos:], "..."):
+		p.fail("fragments are not supported")
+	case c == '"':
+		p.pos++
+		for p.pos < len(p.src) && p.src[p.pos] != '"' {
+			if p.src[p.pos] == '\\' {
+				p.pos++
+			}
+			p.pos++
+		}
+		if p.pos >= len(p.src) {
+			p.fail("unterminated string")
+		}
+		p.pos++
+		s, err := strconv.Unquote(p.src[start:p.pos])
+		if err != nil {
+			p.fail("bad string %s", p.src[start:p.pos])
+		}
+		p.tok, p.str = s, true
+		return
+	case c == '_' || c == '-' || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)):
+		for p.pos < len(p.src) {
+			c := rune(p.src[p.pos])
+			if c != '_' && c != '-' && c != '.' && !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+				break
+			}
+			p.pos++
+		}
+	default:
+		p.pos++
+	}
+	p.tok = p.src[start:p.pos]
+}
+
+func (p *gqlParser) expect(tok string) {
+	if p.tok != tok || p.str {
+		p.fail("%q expected, got %q", tok, p.tok)
+	}
+	p.next()
+}
+
+func (p *gqlParser) name() string {
+	if p.str || p.tok == "" || !(p.tok[0] == '_' || unicode.IsLetter(rune(p.tok[0]))) {
+		p.fail("name expected, got %q", p.tok)
+	}
+	n := p.tok
+	p.next()
+	return n
+}
+
+func (p *gqlParser) operation() *gqlOperation {
+	op := &gqlOperation{kind: "query", defaults: make(map[string]interface{})}
+	if p.tok != "{" {
+		op.kind = p.name()
+		if op.kind != "query" && op.kind != "mutation" {
+			p.fail("%s operations are not supported", op.kind)
+		}
+		if p.tok != "{" && p.tok != "(" {
+			op.name = p.name()
+		}
+		if p.tok == "(" {
+			p.next()
+			for p.tok != ")" {
+				p.expect("$")
+				v := p.name()
+				p.expect(":")
+				p.skipType()
+				if p.tok == "=" {
+					p.next()
+					op.defaults[v] = p.value()
+				}
+			}
+			p.next()
+		}
+	}
+	if p.tok == "@" {
+		p.fail("directives are not supported")
+	}
+	op.selections = p.selectionSet()
+	return op
+}
+
+func (p *gqlParser) skipType() {
+	if p.tok == "[" {
+		p.next()
+		p.skipType()
+		p.expect("]")
+	} else {
+		p.name()
+	}
+	if p.tok == "!" {
+		p.next()
+	}
+}
+
+func (p *gqlParser) selectionSet() []*gqlField {
+	p.expect("{")
+	var fields []*gqlField
+	for p.tok != "}" {
+		if p.tok == "" {
+			p.fail("unterminated selection")
+		}
+		f := &gqlField{name: p.name()}
+		if p.tok == ":" {
+			p.next()
+			f.alias, f.name = f.name, p.name()
+		}
+		if p.tok == "(" {
+			p.next()
+			f.args = make(map[string]interface{})
+			for p.tok != ")" {
+				a := p.name()
+				p.expect(":")
+				f.args[a] = p.value()
+			}
+			p.next()
+		}
+		if p.tok == "@" {
+			p.fail("directives are not supported")
+		}
+		if p.tok == "{" {
+			f.selections = p.selectionSet()
+		}
+		fields = append(fields, f)
+	}
+	p.next()
+	return fields
+}
+
+func (p *gqlParser) value() interface{} {
+	if p.str {
+		s := p.tok
+		p.next()
+		return s
+	}
+	switch p.tok {
+	case "$":
+		p.next()
+		return gqlVariable(p.name())
+	case "true", "false":
+		b := p.tok == "true"
+		p.next()
+		return b
+	case "null":
+		p.next()
+		return nil
+	case "[":
+		p.next()
+		l := []interface{}{}
+		for p.tok != "]" {
+			l = append(l, p.value())
+		}
+		p.next()
+		return l
+	case "{":
+		p.next()
+		o := make(map[string]interface{})
+		for p.tok != "}" {
+			k := p.name()
+			p.expect(":")
+			o[k] = p.value()
+		}
+		p.next()
+		return o
+	}
+	if n, err := strconv.ParseFloat(p.tok, 64); err == nil {
+		p.next()
+		return n
+	}
+	return p.name() // enum value
+}
+
+// Execution.  Objects resolve fields by name, results keep the order of
+// the selections.
+
+type gqlObject interface {
+	field(x *gqlExecution, name string, args map[string]interface{}) (interface{}, error)
+}
+
+type gqlEntry struct {
+	key   string
+	value interface{}
+}
+
+type gqlResult []gqlEntry
+
+func (r gqlResult) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, e := range r {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, _ := json.Marshal(e.key)
+		v, err := json.Marshal(e.value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// Limits on the work of a single request
+const (
+	gqlMaxDepth   = 16
+	gqlMaxObjects = 10000
+)
+
+type gqlExecution struct {
+	d         *database // snapshot for queries, working copy for mutations
+	r         *http.Request
+	variables map[string]interface{}
+	errors    []string
+	changed   bool     // whether a mutation modified the database
+	objects   int      // number of objects resolved so far
+	tree      *gqlTree // index of d, nil until needed
+}
+
+// Nodes by ID, paths, parents, leaf counts and shared nodes of the tree,
+// computed once per execution rather than once per node
+type gqlTree struct {
+	ids        map[string]*node
+	paths      map[*node]string
+	parents    map[*node]*node
+	leafCounts map[*node]int
+	shared     map[*node]bool
+}
+
+func (x *gqlExecution) index() *gqlTree {
+	if x.tree != nil {
+		return x.tree
+	}
+	t := &gqlTree{ids: make(map[string]*node), paths: make(map[*node]string), parents: make(map[*node]*node),
+		leafCounts: make(map[*node]int), shared: sharedNodes(x.d.Root)}
+	var walk func(n *node, path []byte) int
+	walk = func(n *node, path []byte) int {
+		if _, seen := t.paths[n]; seen {
+			return t.leafCounts[n]
+		}
+		t.ids[n.ID] = n
+		t.paths[n] = string(path)
+		count := 1
+		if !n.isLeaf() {
+			for _, c := range []*node{n.No, n.Yes} {
+				if t.parents[c] == nil {
+					t.parents[c] = n
+				}
+			}
+			count = walk(n.No, append(path, 'n')) + walk(n.Yes, append(path, 'y'))
+		}
+		t.leafCounts[n] = count
+		return count
+	}
+	walk(x.d.Root, nil)
+	if len(t.shared) > 0 {
+		// Sums count leaves reachable by several paths several times.
+		t.leafCounts = make(map[*node]int)
+	}
+	x.tree = t
+	return t
+}
+
+func (t *gqlTree) leafCount(n *node) int {
+	c, ok := t.leafCounts[n]
+	if !ok {
+		c = len(leaves(n))
+		t.leafCounts[n] = c
+	}
+	return c
+}
+
+// Record that a mutation modified the database
+func (x *gqlExecution) change() {
+	x.changed = true
+	x.tree = nil
+}
+
+// Count object about to be resolved against the budget of the request
+func (x *gqlExecution) spend() error {
+	x.objects++
+	if x.objects > gqlMaxObjects {
+		return fmt.Errorf("more than %d objects requested", gqlMaxObjects)
+	}
+	return nil
+}
+
+// Depth of nested selections
+func selectionDepth(fields []*gqlField) int {
+	depth := 0
+	for _, f := range fields {
+		if d := selectionDepth(f.selections); d > depth {
+			depth = d
+		}
+	}
+	if len(fields) > 0 {
+		depth++
+	}
+	return depth
+}
+
+func (x *gqlExecution) execute(obj gqlObject, fields []*gqlField) gqlResult {
+	res := gqlResult{}
+	for _, f := range fields {
+		key := f.name
+		if f.alias != "" {
+			key = f.alias
+		}
+		args := make(map[string]interface{})
+		for k, v := range f.args {
+			args[k] = x.resolveVariables(v)
+		}
+		var v interface{}
+		var err error
+		if f.name == "__typename" {
+			v = strings.TrimPrefix(fmt.Sprintf("%T", obj), "*main.gql")
+		} else {
+			v, err = obj.field(x, f.name, args)
+		}
+		if err == nil {
+			v, err = x.complete(f, v)
+		}
+		if err != nil {
+			x.errors = append(x.errors, fmt.Sprintf("%s: %s", key, err))
+			v = nil
+		}
+		res = append(res, gqlEntry{key, v})
+	}
+	return res
+}
+
+func (x *gqlExecution) resolveVariables(v interface{}) interface{} {
+	switch v := v.(type) {
+	case gqlVariable:
+		return x.variables[string(v)]
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, e := range v {
+			l[i] = x.resolveVariables(e)
+		}
+		return l
+	case map[string]interface{}:
+		o := make(map[string]interface{})
+		for k, e := range v {
+			o[k] = x.resolveVariables(e)
+		}
+		return o
+	}
+	return v
+}
+
+// Result of field f whose resolver returned v
+func (x *gqlExecution) complete(f *gqlField, v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	switch v := v.(type) {
+	case gqlObject:
+		if f.selections == nil {
+			return nil, fmt.Errorf("selection of subfields expected")
+		}
+		if err := x.spend(); err != nil {
+			return nil, err
+		}
+		return x.execute(v, f.selections), nil
+	case []gqlObject:
+		if f.selections == nil {
+			return nil, fmt.Errorf("selection of subfields expected")
+		}
+		l := []gqlResult{}
+		for _, o := range v {
+			if err := x.spend(); err != nil {
+				return nil, err
+			}
+			l = append(l, x.execute(o, f.selections))
+		}
+		return l, nil
+	}
+	if f.selections != nil {
+		return nil, fmt.Errorf("scalar field has no subfields")
+	}
+	return v, nil
+}
+
+func stringArg(args map[string]interface{}, name string) (string, error) {
+	s, ok := args[name].(string)
+	if !ok {
+		return "", fmt.Errorf("string argument %s expected", name)
+	}
+	return s, nil
+}
+
+func boolArg(args map[string]interface{}, name string) (bool, error) {
+	b, ok := args[name].(bool)
+	if !ok {
+		return false, fmt.Errorf("boolean argument %s expected", name)
+	}
+	return b, nil
+}
+
+// Resolvers
+
+type gqlQuery struct{}
+
+type gqlMutation struct{}
+
+type gqlNode struct {
+	d *database
+	n *node
+}
+
+type gqlStats struct {
+	entries []animalEntry
+	d       *database
+}
+
+func toGQLNodes(d *database, nodes []*node) []gqlObject {
+	l := []gqlObject{}
+	for _, n := range nodes {
+		l = append(l, &gqlNode{d, n})
+	}
+	return l
+}
+
+// Animals or questions of subtree root.  Fails as soon as they exceed the
+// budget of the request rather than once all are collected.
+func (x *gqlExecution) nodes(root *node, animals bool) ([]gqlObject, error) {
+	l := []gqlObject{}
+	seen := make(map[*node]bool)
+	var walk func(n *node) bool
+	walk = func(n *node) bool {
+		if n == nil || seen[n] {
+			return true
+		}
+		seen[n] = true
+		if n.isLeaf() == animals {
+			if x.objects+len(l) >= gqlMaxObjects {
+				return false
+			}
+			l = append(l, &gqlNode{x.d, n})
+		}
+		return walk(n.No) && walk(n.Yes)
+	}
+	if !walk(root) {
+		return nil, fmt.Errorf("more than %d objects requested", gqlMaxObjects)
+	}
+	return l, nil
+}
+
+// Node named by id argument, nil if none
+func (x *gqlExecution) nodeArg(args map[string]interface{}) (*node, error) {
+	id, err := stringArg(args, "id")
+	if err != nil {
+		return nil, err
+	}
+	return x.index().ids[id], nil
+}
+
+func wrapNode(d *database, n *node) gqlObject {
+	if n == nil {
+		return nil
+	}
+	return &gqlNode{d, n}
+}
+
+func (gqlQuery) field(x *gqlExecution, name string, args map[string]interface{}) (interface{}, error) {
+	d := x.d
+	switch name {
+	case "category":
+		return d.category(), nil
+	case "language":
+		if d.Language == "" {
+			return nil, nil
+		}
+		return d.Language, nil
+	case "root":
+		return wrapNode(d, d.Root), nil
+	case "node":
+		n, err := x.nodeArg(args)
+		return wrapNode(d, n), err
+	case "path":
+		answers, err := stringArg(args, "answers")
+		if err != nil {
+			return nil, err
+		}
+		n := d.Root
+		for _, a := range answers {
+			if n.isLeaf() || (a != 'y' && a != 'n') {
+				return nil, nil
+			}
+			if a == 'y' {
+				n = n.Yes
+			} else {
+				n = n.No
+			}
+		}
+		return wrapNode(d, n), nil
+	case "animal":
+		a, err := stringArg(args, "name")
+		if err != nil {
+			return nil, err
+		}
+		return wrapNode(d, d.findAnimal(a)), nil
+	case "search":
+		a, err := stringArg(args, "name")
+		if err != nil {
+			return nil, err
+		}
+		return toGQLNodes(d, d.search(a)), nil
+	case "animals":
+		return x.nodes(d.Root, true)
+	case "questions":
+		return x.nodes(d.Root, false)
+	case "stats":
+		return &gqlStats{listAnimals(d.Root, 0), d}, nil
+	}
+	return nil, fmt.Errorf("unknown field")
+}
+
+func (gqlMutation) field(x *gqlExecution, name string, args map[string]interface{}) (interface{}, error) {
+	d := x.d
+	if name != "teach" && !isCurator(x.r) {
+		return nil, fmt.Errorf("curator credentials required")
+	}
+	n, err := x.nodeArg(args)
+	if err != nil {
+		return nil, err
+	}
+	if n == nil {
+		return nil, fmt.Errorf("unknown node")
+	}
+	switch name {
+	case "teach":
+		return x.teach(n, args)
+	case "renameAnimal":
+		text, err := stringArg(args, "name")
+		if err != nil {
+			return nil, err
+		}
+		if !n.isLeaf() {
+			return nil, fmt.Errorf("animal expected")
+		}
+		if _, err := d.review(n, "edit", strings.TrimSpace(text)); err != nil {
+			return nil, err
+		}
+		x.change()
+		return wrapNode(d, n), nil
+	case "editQuestion":
+		text, err := stringArg(args, "question")
+		if err != nil {
+			return nil, err
+		}
+		if n.isLeaf() {
+			return nil, fmt.Errorf("question expected")
+		}
+		if _, err := d.review(n, "edit", strings.TrimSpace(text)); err != nil {
+			return nil, err
+		}
+		x.change()
+		return wrapNode(d, n), nil
+	case "deleteAnimal":
+		if _, err := d.review(n, "delete", ""); err != nil {
+			return nil, err
+		}
+		x.change()
+		return true, nil
+	}
+	return nil, fmt.Errorf("unknown field")
+}
+
+// Insert animal above node n, as a game would after failing to guess it
+func (x *gqlExecution) teach(n *node, args map[string]interface{}) (interface{}, error) {
+	d := x.d
+	animal, err := stringArg(args, "animal")
+	if err != nil {
+		return nil, err
+	}
+	question, err := stringArg(args, "question")
+	if err != nil {
+		return nil, err
+	}
+	yes, err := boolArg(args, "yes")
+	if err != nil {
+		return nil, err
+	}
+	animal, question = strings.TrimSpace(animal), strings.TrimSpace(question)
+	if animal == "" || question == "" {
+		return nil, fmt.Errorf("animal and question expected")
+	}
+	if other := d.findAnimal(animal); other != nil {
+		return nil, fmt.Errorf("%s already known", other.Animal)
+	}
+	if d.full() {
+		return nil, fmt.Errorf("database full")
+	}
+	leaf := &node{Animal: animal}
+	v, animalReason := moderate(animal, false)
+	if v == reject {
+		return nil, fmt.Errorf("%s", animalReason)
+	}
+	if v == flagged {
+		leaf.Flagged = animalReason
+	}
+	v, questionReason := moderate(question, true)
+	if v == reject {
+		return nil, fmt.Errorf("%s", questionReason)
+	}
+	d.learn(n, leaf, question, yes)
+	if v == flagged {
+		n.Flagged = questionReason
+	}
+	x.change()
+	return wrapNode(d, d.prune(leaf)), nil
+}
+
+func (o *gqlNode) field(x *gqlExecution, name string, args map[string]interface{}) (interface{}, error) {
+	n, d := o.n, o.d
+	optional := func(s string) interface{} {
+		if s == "" {
+			return nil
+		}
+		return s
+	}
+	switch name {
+	case "id":
+		return n.ID, nil
+	case "text":
+		return n.text(), nil
+	case "question":
+		return optional(n.Question), nil
+	case "animal":
+		return optional(n.Animal), nil
+	case "isAnimal":
+		return n.isLeaf(), nil
+	case "path":
+		return x.index().paths[n], nil
+	case "yes":
+		return wrapNode(d, n.Yes), nil
+	case "no":
+		return wrapNode(d, n.No), nil
+	case "parent":
+		return wrapNode(d, x.index().parents[n]), nil
+	case "animals":
+		return x.nodes(n, true)
+	case "leafCount":
+		return x.index().leafCount(n), nil
+	case "yesCount":
+		return n.YesCount, nil
+	case "noCount":
+		return n.NoCount, nil
+	case "chosenCount":
+		return n.ChosenCount, nil
+	case "guessCount":
+		return n.GuessCount, nil
+	case "confusingCount":
+		return n.ConfusingCount, nil
+	case "description":
+		return optional(n.Description), nil
+	case "imageURL":
+		return optional(n.ImageURL), nil
+	case "flagged":
+		return optional(n.Flagged), nil
+	case "shared":
+		return x.index().shared[n], nil
+	}
+	return nil, fmt.Errorf("unknown field")
+}
+
+func (st *gqlStats) field(x *gqlExecution, name string, args map[string]interface{}) (interface{}, error) {
+	minDepth, maxDepth, total, games := st.entries[0].Depth, 0, 0, 0
+	for _, e := range st.entries {
+		if e.Depth < minDepth {
+			minDepth = e.Depth
+		}
+		if e.Depth > maxDepth {
+			maxDepth = e.Depth
+		}
+		total += e.Depth
+		games += e.ChosenCount
+	}
+	switch name {
+	case "games":
+		return games, nil
+	case "animals":
+		return len(st.entries), nil
+	case "questions":
+		return len(questionNodes(st.d.Root)), nil
+	case "nodes":
+		return nodeCount(st.d.Root), nil
+	case "minDepth":
+		return minDepth, nil
+	case "maxDepth":
+		return maxDepth, nil
+	case "averageDepth":
+		return float64(total) / float64(len(st.entries)), nil
+	}
+	return nil, fmt.Errorf("unknown field")
+}
+
+type gqlRequest struct {
+	Query         string
+	OperationName string
+	Variables     map[string]interface{}
+}
+
+func (s *server) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	var req gqlRequest
+	switch r.Method {
+	case "GET":
+		req.Query = r.FormValue("query")
+		req.OperationName = r.FormValue("operationName")
+		if v := r.FormValue("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				http.Error(w, "bad variables: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+	case "POST":
+		if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			http.Error(w, "JSON request expected", http.StatusUnsupportedMediaType)
+			return
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+			http.Error(w, "bad request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ops, err := parseGraphQL(req.Query)
+	if err != nil {
+		writeGraphQLErrors(w, err.Error())
+		return
+	}
+	var op *gqlOperation
+	for _, o := range ops {
+		if o.name == req.OperationName || (req.OperationName == "" && len(ops) == 1) {
+			op = o
+		}
+	}
+	if op == nil {
+		writeGraphQLErrors(w, "operation not found")
+		return
+	}
+	if selectionDepth(op.selections) > gqlMaxDepth {
+		writeGraphQLErrors(w, fmt.Sprintf("query deeper than %d levels", gqlMaxDepth))
+		return
+	}
+	if op.kind == "mutation" && r.Method != "POST" {
+		http.Error(w, "mutations must be posted", http.StatusMethodNotAllowed)
+		return
+	}
+
+	x := &gqlExecution{r: r, variables: op.defaults}
+	for k, v := range req.Variables {
+		x.variables[k] = v
+	}
+	var data gqlResult
+	if op.kind == "mutation" {
+		data = s.mutate(x, op.selections)
+	} else {
+		x.d = s.view()
+		data = x.execute(gqlQuery{}, op.selections)
+	}
+	out := map[string]interface{}{"data": data}
+	if len(x.errors) > 0 {
+		out["errors"] = gqlMessages(x.errors)
+	}
+	writeJSON(w, out)
+}
+
+// Execute mutation on database and save it if changed
+func (s *server) mutate(x *gqlExecution, fields []*gqlField) gqlResult {
+	s.Lock()
+	defer s.Unlock()
+	x.d = s.db
+	data := x.execute(gqlMutation{}, fields)
+	if x.changed {
+		s.save()
+	}
+	return data
+}
+
+func gqlMessages(errors []string) []map[string]string {
+	l := []map[string]string{}
+	for _, e := range errors {
+		l = append(l, map[string]string{"message": e})
+	}
+	return l
+}
+
+func writeGraphQLErrors(w http.ResponseWriter, errors ...string) {
+	writeJSON(w, map[string]interface{}{"errors": gqlMessages(errors)})
+}
+
+func handleGraphQLSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(graphQLSchema))
+}
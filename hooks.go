@@ -0,0 +1,140 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Hooks notified of game events, e.g. to drive lights or sounds in kiosk
+// installations, configured by the user (see config.go).
+//
+// The command hook receives the event in $ASK_AND_LEARN_EVENT, the animal
+// guessed or learned if any in $ASK_AND_LEARN_ANIMAL and the JSON event on
+// stdin.  The webhook receives the JSON event.  Hooks run in the background
+// so that they do not slow games down; failures are reported and otherwise
+// ignored.
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+const (
+	gameStarted   = "start"
+	guessCorrect  = "correct-guess"
+	guessFailed   = "failed-guess"
+	animalLearned = "learned"
+)
+
+// Event as sent to hooks
+type event struct {
+	Event     string
+	Category  string
+	Animal    string `json:",omitempty"`
+	Questions int
+}
+
+// Maximum duration of hooks
+const hookTimeout = 5 * time.Second
+
+// Hooks waiting to run, one after the other so that they see events in
+// order
+var (
+	hookQueue chan func()
+	hookOnce  sync.Once
+	hooksDone sync.WaitGroup
+)
+
+func queueHook(f func()) {
+	hookOnce.Do(func() {
+		hookQueue = make(chan func(), 64)
+		hooksDone.Add(1)
+		go func() {
+			defer hooksDone.Done()
+			for f := range hookQueue {
+				f()
+			}
+		}()
+	})
+	hookQueue <- f
+}
+
+// Fire hooks for event about leaf, nil if none
+func (g *game) notify(name string, leaf *node) {
+	c := settings()
+	if len(c.HookCommand) == 0 && c.HookURL == "" {
+		return
+	}
+	e := event{Event: name, Category: g.db.category(), Questions: g.questions}
+	if leaf != nil {
+		e.Animal = leaf.Animal
+	}
+	body, _ := json.Marshal(e)
+	queueHook(func() {
+		if len(c.HookCommand) > 0 {
+			runHookCommand(c.HookCommand, e, body)
+		}
+		if c.HookURL != "" {
+			postHook(c.HookURL, body)
+		}
+	})
+}
+
+func runHookCommand(argv []string, e event, body []byte) {
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(), "ASK_AND_LEARN_EVENT="+e.Event, "ASK_AND_LEARN_ANIMAL="+e.Animal)
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintln(os.Stderr, "can not run hook command:", err)
+		return
+	}
+	timer := time.AfterFunc(hookTimeout, func() { cmd.Process.Kill() })
+	defer timer.Stop()
+	if err := cmd.Wait(); err != nil {
+		fmt.Fprintln(os.Stderr, "hook command failed:", err)
+	}
+}
+
+func postHook(url string, body []byte) {
+	client := http.Client{Timeout: hookTimeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "can not reach hook:", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		fmt.Fprintln(os.Stderr, "hook failed:", resp.Status)
+	}
+}
+
+// Let queued hooks run before exiting
+func waitHooks() {
+	if hookQueue != nil {
+		close(hookQueue)
+		hooksDone.Wait()
+	}
+}
@@ -0,0 +1,121 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Hotseat matches: players take turns choosing an animal and answering while
+// the others look away, and score when the program fails to find it.  The
+// next player in turn secretly teaches the animals the program did not know.
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Comma-separated list flag
+type commaList []string
+
+func (l *commaList) String() string { return strings.Join(*l, ",") }
+
+func (l *commaList) Set(v string) error {
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			*l = append(*l, s)
+		}
+	}
+	return nil
+}
+
+var players commaList
+
+type playerScore struct {
+	name   string
+	games  int
+	stumps int // games the program lost
+	taught int // animals taught
+	asked  int // questions answered
+}
+
+func playMatch() {
+	scores := make([]*playerScore, len(players))
+	for i, name := range players {
+		scores[i] = &playerScore{name: name}
+	}
+	games := *matchGames
+	if games <= 0 {
+		games = 2 * len(players)
+	}
+
+	for i := 0; i < games; i++ {
+		s := scores[i%len(scores)]
+		teacher := scores[(i+1)%len(scores)]
+		fmt.Println()
+		fmt.Printf(tr("Game %d of %d: %s, pick one %s and answer the questions."), i+1, games, s.name, tr(db.category()))
+		if len(scores) > 1 {
+			fmt.Print(" " + tr("The others look away!"))
+		}
+		name := ""
+		if teacher != s {
+			name = teacher.name
+			fmt.Printf(" "+tr("%s teaches me what I do not know."), name)
+		}
+		fmt.Println()
+		g := playOneGame(s.name, name)
+		if g.abandoned || g.paused {
+			// Nothing was played out.
+			continue
+		}
+		s.games++
+		s.asked += g.questions
+		if !g.found && !g.forfeited {
+			s.stumps++
+			fmt.Printf(tr("Point for %s!")+"\n", s.name)
+		}
+		if g.taught {
+			teacher.taught++
+		}
+	}
+
+	fmt.Println("\n" + tr("Final score:"))
+	best := 0
+	for _, s := range scores {
+		fmt.Printf("  %-12s "+tr("%d point(s), %d game(s), %d animal(s) taught, %d question(s) answered")+"\n",
+			s.name, s.stumps, s.games, s.taught, s.asked)
+		if s.stumps > best {
+			best = s.stumps
+		}
+	}
+	var winners []string
+	for _, s := range scores {
+		if s.stumps == best {
+			winners = append(winners, s.name)
+		}
+	}
+	switch {
+	case best == 0:
+		fmt.Println(tr("Nobody stumped me!"))
+	case len(winners) == 1:
+		fmt.Printf(tr("%s wins!")+"\n", winners[0])
+	default:
+		fmt.Printf(tr("Tie between %s.")+"\n", strings.Join(winners, " "+tr("and")+" "))
+	}
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// User-facing strings are written in English in the source and translated
+// at run time through message catalogs embedded from locales/LANG.json,
+// which map English strings to their translations.  Missing translations
+// fall back to English.
+
+import (
+	"embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+//go:embed locales/*.json
+var locales embed.FS
+
+var langFlag = flag.String("lang", "", "language of prompts, e.g. fr (default: from $LANG)")
+
+var (
+	catalogOnce sync.Once
+	catalog     map[string]string
+)
+
+// Translation of msg in selected language
+func tr(msg string) string {
+	catalogOnce.Do(loadCatalog)
+	if t, ok := catalog[msg]; ok {
+		return t
+	}
+	return msg
+}
+
+func loadCatalog() {
+	lang := language()
+	if lang == "" || lang == "en" {
+		return
+	}
+	content, err := locales.ReadFile("locales/" + lang + ".json")
+	if err != nil {
+		if *langFlag != "" {
+			fmt.Fprintf(os.Stderr, "no translation for language %q\n", lang)
+		}
+		return
+	}
+	err = json.Unmarshal(content, &catalog)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "can not read catalog for %q: %s\n", lang, err)
+	}
+}
+
+// Language selected by flag or environment, e.g. "fr" for "fr_FR.UTF-8"
+func language() string {
+	lang := *langFlag
+	for _, v := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if lang != "" {
+			break
+		}
+		lang = os.Getenv(v)
+	}
+	if i := strings.IndexAny(lang, "_.@"); i >= 0 {
+		lang = lang[:i]
+	}
+	return strings.ToLower(lang)
+}
@@ -0,0 +1,50 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+import "strings"
+
+// Console able to display pictures, such as the web client of rooms
+type imageViewer interface {
+	showImage(url string)
+}
+
+// Show picture of leaf about to be guessed if any and possible
+func (g *game) showImage(leaf *node) {
+	if v, ok := g.ui.(imageViewer); ok && leaf.ImageURL != "" {
+		v.showImage(leaf.ImageURL)
+	}
+}
+
+// Split list line into name and optional trailing image URL
+func splitImageURL(line string) (name, url string) {
+	i := strings.LastIndexAny(line, " \t")
+	if i < 0 {
+		return line, ""
+	}
+	last := line[i+1:]
+	if !strings.HasPrefix(last, "http://") && !strings.HasPrefix(last, "https://") {
+		return line, ""
+	}
+	return strings.TrimSpace(line[:i]), last
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Kid-friendly mode: simpler wording, decorated output and checks on what
+// players teach.
+
+import (
+	"flag"
+	"unicode/utf8"
+)
+
+var kidsFlag = flag.Bool("kids", false, "kid-friendly wording, decorated output and content checks")
+
+// Maximum length in characters of questions taught in kid-friendly mode
+const kidsMaxQuestion = 60
+
+// Words refused in taught content in kid-friendly mode
+var kidsBlocklist = map[string]bool{
+	"ass":    true,
+	"bitch":  true,
+	"crap":   true,
+	"damn":   true,
+	"dumb":   true,
+	"fuck":   true,
+	"hate":   true,
+	"hell":   true,
+	"idiot":  true,
+	"kill":   true,
+	"poop":   true,
+	"shit":   true,
+	"stupid": true,
+	"sucks":  true,
+}
+
+// Phrasing overriding the category one in kid-friendly mode
+func kidsPhrasing(p *phrasing) *phrasing {
+	return &phrasing{
+		guess:       p.guess,
+		unknown:     "Oh no, I did not find it! Which %s was it?",
+		distinguish: "How can I tell %s from %s? Give me a yes-or-no question:",
+		expected:    "And for %s, is the answer yes or no?",
+		article:     p.article,
+		first:       p.first,
+	}
+}
+
+// Console with emoji and spacing to make prompts stand out
+type kidsConsole struct {
+	console
+}
+
+func (c kidsConsole) ask(prompt string) string {
+	c.console.tell("")
+	return c.console.ask("🤔  " + prompt)
+}
+
+func (c kidsConsole) askYesNo(prompt string) bool {
+	c.console.tell("")
+	return c.console.askYesNo("🤔  " + prompt)
+}
+
+func (c kidsConsole) askGuess(prompt string) bool {
+	c.console.tell("")
+	if gs, ok := c.console.(guesser); ok {
+		return gs.askGuess("🤔  " + prompt)
+	}
+	return c.console.askYesNo("🤔  " + prompt)
+}
+
+func (c kidsConsole) showImage(url string) {
+	if v, ok := c.console.(imageViewer); ok {
+		v.showImage(url)
+	}
+}
+
+func (c kidsConsole) tell(msg string) {
+	if msg != "" {
+		msg = "⭐  " + msg
+	}
+	c.console.tell(msg)
+}
+
+// Reason why text can not be taught or empty string
+func checkContent(text string, isQuestion bool) string {
+	if !*kidsFlag {
+		return ""
+	}
+	for _, w := range words(text) {
+		if kidsBlocklist[w] {
+			return tr("Let's keep it friendly, please use other words.")
+		}
+	}
+	if isQuestion && utf8.RuneCountInString(text) > kidsMaxQuestion {
+		return tr("That is a bit long, can you make it shorter?")
+	}
+	return ""
+}
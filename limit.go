@@ -0,0 +1,110 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Size limit of trees, so that trees of open servers can not grow without
+// bound.  When the limit is reached, the "reject" policy (the default)
+// stops teaching while the "prune" one forgets the obscure animals that
+// players chose least recently.
+
+import (
+	"fmt"
+	"os"
+)
+
+const (
+	rejectPolicy = "reject"
+	prunePolicy  = "prune"
+)
+
+func sizePolicy() string {
+	if p := settings().SizePolicy; p != "" {
+		return p
+	}
+	return rejectPolicy
+}
+
+// Exit on unknown policy of settings c being loaded
+func checkSizePolicy(c *config) {
+	switch c.SizePolicy {
+	case "", rejectPolicy, prunePolicy:
+	default:
+		fmt.Fprintf(os.Stderr, "%s: unknown size policy %q\n", configPath(), c.SizePolicy)
+		os.Exit(1)
+	}
+}
+
+// Whether teaching would exceed the limit and must be refused
+func (d *database) full() bool {
+	max := settings().MaxNodes
+	return max > 0 && sizePolicy() == rejectPolicy && nodeCount(d.Root)+2 > max
+}
+
+// Remove animals until tree fits within limit, the least recently chosen
+// and then the least chosen first, sparing keep, if any, which is returned.
+// As each removal is an op, the op log is folded into the base tree when
+// it gets larger than the limit, unless other copies merged with it as they
+// could then no longer do so.
+func (d *database) prune(keep *node) *node {
+	max := settings().MaxNodes
+	if max <= 0 || sizePolicy() != prunePolicy {
+		return keep
+	}
+	for nodeCount(d.Root) > max {
+		var victim *node
+		for _, leaf := range leaves(d.Root) {
+			if keep != nil && leaf.ID == keep.ID {
+				continue
+			}
+			if victim == nil || leaf.LastChosen < victim.LastChosen ||
+				leaf.LastChosen == victim.LastChosen && leaf.ChosenCount < victim.ChosenCount {
+				victim = leaf
+			}
+		}
+		if victim == nil || !d.remove(victim) {
+			break
+		}
+	}
+	switch {
+	case len(d.Ops) <= max:
+	case d.hasPeers():
+		if !warnedOpLog {
+			warnedOpLog = true
+			fmt.Fprintf(os.Stderr, "op log has %d ops, more than the %d nodes allowed, but is kept to merge with other copies\n", len(d.Ops), max)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "op log folded into base tree to stay within %d nodes\n", max)
+		d.rebase()
+	}
+	return keep
+}
+
+// Whether prune reported keeping op log larger than limit
+var warnedOpLog bool
+
+// Whether merging learn ops would exceed the limit under the reject
+// policy, nodes being the number of nodes once merged so far
+func rejectsMerge(nodes int) bool {
+	max := settings().MaxNodes
+	return max > 0 && sizePolicy() == rejectPolicy && nodes+2 > max
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Speech recognition of answers by an external command run each time an
+// answer is expected, which records the player and prints what was said.
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+var listenCommand = flag.String("listen-cmd", "", "speech recognition command printing the next answer of the player instead of reading stdin")
+
+// Signalled when an answer is expected
+var listenRequests = make(chan struct{}, 1)
+
+// Stops the recognition in progress if any
+var (
+	listening     sync.Mutex
+	stopListening = func() {}
+)
+
+// Failed recognitions in a row after which answers are read from stdin
+const maxListenFailures = 3
+
+var errNothingSaid = errors.New("nothing recognized")
+
+// Run recognition command on request and feed its output as input lines.
+// A failed recognition is retried, and stdin is read instead once the
+// command can not be started or fails maxListenFailures times in a row.
+func startListening() {
+	argv := strings.Fields(*listenCommand)
+	go func() {
+		failures := 0
+		for range listenRequests {
+			ctx, cancel := context.WithCancel(context.Background())
+			listening.Lock()
+			stopListening = cancel
+			listening.Unlock()
+			var s string
+			for {
+				said, err := exec.CommandContext(ctx, argv[0], argv[1:]...).Output()
+				// Recognizers tend to punctuate sentences.
+				s = strings.Trim(strings.TrimSpace(string(said)), ".!?,")
+				if err == nil && s == "" {
+					err = errNothingSaid
+				}
+				if err == nil || ctx.Err() != nil {
+					break
+				}
+				failures++
+				_, exited := err.(*exec.ExitError)
+				started := exited || err == errNothingSaid
+				if !started || failures >= maxListenFailures {
+					cancel()
+					fmt.Fprintln(os.Stderr, "speech recognition failed, reading answers from stdin:", err)
+					readStdin()
+					return
+				}
+				fmt.Fprintln(os.Stderr, "speech recognition failed, listening again:", err)
+			}
+			if ctx.Err() != nil {
+				// Nobody waits for this answer anymore.
+				continue
+			}
+			failures = 0
+			fmt.Fprintln(out, s)
+			select {
+			case lines <- inputLine{s + "\n", nil, time.Now()}:
+			case <-ctx.Done():
+			}
+			cancel()
+		}
+	}()
+}
+
+// Exit if recognition command is requested but can not be found
+func checkListenCommand() {
+	argv := strings.Fields(*listenCommand)
+	if len(argv) == 0 {
+		*listenCommand = ""
+		return
+	}
+	if _, err := exec.LookPath(argv[0]); err != nil {
+		fmt.Fprintln(os.Stderr, "can not run speech recognition command:", err)
+		os.Exit(1)
+	}
+}
+
+// Drop answer being recognized, e.g. when time is up
+func cancelListening() {
+	listening.Lock()
+	defer listening.Unlock()
+	stopListening()
+}
+
+// Ask recognition command for an answer unless already asked
+func listen() {
+	if *listenCommand == "" {
+		return
+	}
+	select {
+	case listenRequests <- struct{}{}:
+	default:
+	}
+}
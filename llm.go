@@ -0,0 +1,142 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Optional help from a large language model served by an OpenAI-compatible
+// endpoint configured in the user config (see config.go).  The API key is
+// read from $OPENAI_API_KEY unless the config names another variable.
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+var suggestFlag = flag.Bool("suggest", false, "let the language model suggest distinguishing questions")
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Reply of the model to conversation
+func chat(messages []chatMessage) (string, error) {
+	c := settings()
+	if c.LLMURL == "" {
+		return "", errors.New("LLMURL not set in config")
+	}
+	body, err := json.Marshal(struct {
+		Model    string        `json:"model"`
+		Messages []chatMessage `json:"messages"`
+	}{c.LLMModel, messages})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest("POST", strings.TrimRight(c.LLMURL, "/")+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	keyVar := c.LLMKeyEnv
+	if keyVar == "" {
+		keyVar = "OPENAI_API_KEY"
+	}
+	if key := os.Getenv(keyVar); key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	client := http.Client{Timeout: 60 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s", resp.Status)
+	}
+	var reply struct {
+		Choices []struct {
+			Message chatMessage `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return "", err
+	}
+	if len(reply.Choices) == 0 {
+		return "", errors.New("empty reply")
+	}
+	return strings.TrimSpace(reply.Choices[0].Message.Content), nil
+}
+
+// Instruction telling the model which language to use if not English
+func languageHint() string {
+	if lang := language(); lang != "" && lang != defaultLanguage {
+		return " Use the language whose code is " + lang + "."
+	}
+	return ""
+}
+
+// Question suggested by the model to distinguish animal from the animals
+// of subtree n
+func (g *game) suggestQuestion(animal string, n *node) (string, bool) {
+	category := g.db.category()
+	q, err := chat([]chatMessage{
+		{"system", "You help build a guessing game tree. Reply with a single short yes-or-no question and nothing else." + languageHint()},
+		{"user", fmt.Sprintf("Which question distinguishes the %s %q from %s?", category, animal, g.db.describeSubtree(n))},
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "can not get suggestion:", err)
+		return "", false
+	}
+	q = strings.TrimSpace(strings.Trim(q, "\""))
+	if q == "" || g.vet(q, true) != "" {
+		return "", false
+	}
+	return q, true
+}
+
+// Question distinguishing animal from the subtree n, suggested by the model
+// and accepted or edited by the player, or typed by the player
+func (g *game) askQuestion(animal string, n *node) string {
+	if *suggestFlag {
+		if q, ok := g.suggestQuestion(animal, n); ok {
+			s := strings.TrimSpace(g.ui.ask(fmt.Sprintf(tr("Suggested question: %s  Use it (yes), not (no) or type a better one:"), q)))
+			if yes, ok := parseYesNo(s); ok {
+				if yes {
+					return q
+				}
+			} else if reason := g.vet(s, true); reason != "" {
+				g.ui.tell(reason)
+			} else {
+				return s
+			}
+		}
+	}
+	p := g.db.phrasing()
+	return g.askContent(fmt.Sprintf(p.distinguish, g.db.named(animal), g.db.describeSubtree(n)), true)
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Marathons: a fixed series of games scoring the program, so that successive
+// versions of a tree can be compared.
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// Points won by the program when it finds the animal, minus one per
+// question, with at least marathonMinWin, and lost when it fails.  Games
+// forfeited, abandoned or paused by the player are worth nothing.
+const (
+	marathonWin     = 20
+	marathonMinWin  = 1
+	marathonPenalty = 10
+)
+
+func marathonPoints(g *game) int {
+	if g.forfeited || g.abandoned || g.paused {
+		return 0
+	}
+	if !g.found {
+		return -marathonPenalty
+	}
+	points := marathonWin - g.questions
+	if points < marathonMinWin {
+		points = marathonMinWin
+	}
+	return points
+}
+
+func playMarathon(games int) {
+	total, wins, questions, played := 0, 0, 0, 0
+	var lines []string
+	for i := 0; i < games; i++ {
+		fmt.Printf("\n"+tr("Game %d of %d")+"\n", i+1, games)
+		g := playOneGame(*playerFlag, "")
+		points := marathonPoints(g)
+		total += points
+		if !g.abandoned && !g.paused {
+			played++
+			questions += g.questions
+		}
+		result := tr("failed")
+		switch {
+		case g.paused:
+			result = tr("paused")
+		case g.abandoned:
+			result = tr("abandoned")
+		case g.found:
+			wins++
+			result = tr("found")
+		case g.forfeited:
+			result = tr("forfeit")
+		}
+		animal := "?"
+		if g.answer != nil {
+			animal = g.answer.Animal
+		}
+		lines = append(lines, fmt.Sprintf("%3d  %-20s %-9s %3d %-12s %+4d", i+1, animal, result, g.questions, tr("question(s)"), points))
+	}
+
+	fmt.Println("\n" + tr("Marathon report:"))
+	for _, l := range lines {
+		fmt.Println("  " + l)
+	}
+	average := 0.0
+	if played > 0 {
+		average = float64(questions) / float64(played)
+	}
+	fmt.Printf(tr("score: %d")+"\n", total)
+	fmt.Printf(tr("found: %d/%d")+"\n", wins, played)
+	fmt.Printf(tr("average questions: %.2f")+"\n", average)
+
+	if *marathonLog != "" {
+		f, err := os.OpenFile(*marathonLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Panic("can not open marathon log: ", err)
+		}
+		defer f.Close()
+		_, err = fmt.Fprintf(f, "%s,%s,%d,%d,%d,%.2f\n", time.Now().Format(time.RFC3339),
+			dbName(dbPaths[0]), played, total, wins, average)
+		if err != nil {
+			log.Panic("can not write marathon log: ", err)
+		}
+	}
+}
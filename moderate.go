@@ -0,0 +1,271 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Moderation of taught content: a built-in word list applied in all modes
+// plus optional hooks configured by the user (see config.go).
+//
+// The command hook receives the text on stdin and the kind of content
+// ("animal" or "question") in $ASK_AND_LEARN_KIND.  Exit status 0 accepts
+// the text, 1 rejects it and 2 flags it for review, output giving the
+// reason.  The webhook receives a JSON {"Text", "Kind"} object and answers
+// a JSON {"Verdict", "Reason"} object, verdict being "allow", "reject" or
+// "flag".  Texts are rejected when a hook fails.
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+type verdict int
+
+const (
+	allow verdict = iota
+	reject
+	flagged
+)
+
+// Words refused in taught content whatever the mode
+var profanity = map[string]bool{
+	"asshole":      true,
+	"bastard":      true,
+	"bitch":        true,
+	"cock":         true,
+	"cunt":         true,
+	"dick":         true,
+	"fuck":         true,
+	"fucking":      true,
+	"motherfucker": true,
+	"pussy":        true,
+	"shit":         true,
+	"slut":         true,
+	"twat":         true,
+	"wank":         true,
+	"whore":        true,
+}
+
+// Maximum duration of hooks
+const moderationTimeout = 5 * time.Second
+
+// Whether text may be taught and why
+func moderate(text string, isQuestion bool) (verdict, string) {
+	for _, w := range words(text) {
+		if profanity[w] {
+			return reject, tr("Please do not use offensive words.")
+		}
+	}
+	kind := "animal"
+	if isQuestion {
+		kind = "question"
+	}
+	c := settings()
+	if len(c.ModerationCommand) > 0 {
+		if v, reason := moderateWithCommand(c.ModerationCommand, text, kind); v != allow {
+			return v, reason
+		}
+	}
+	if c.ModerationURL != "" {
+		return moderateWithURL(c.ModerationURL, text, kind)
+	}
+	return allow, ""
+}
+
+func moderateWithCommand(argv []string, text, kind string) (verdict, string) {
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Env = append(os.Environ(), "ASK_AND_LEARN_KIND="+kind)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintln(os.Stderr, "can not run moderation command:", err)
+		return reject, tr("Sorry, this can not be checked right now.")
+	}
+	timer := time.AfterFunc(moderationTimeout, func() { cmd.Process.Kill() })
+	err := cmd.Wait()
+	timer.Stop()
+	reason := strings.TrimSpace(out.String())
+	if err == nil {
+		return allow, ""
+	}
+	exit, ok := err.(*exec.ExitError)
+	switch {
+	case ok && exit.ExitCode() == 1:
+		return reject, reason
+	case ok && exit.ExitCode() == 2:
+		return flagged, reason
+	}
+	fmt.Fprintln(os.Stderr, "moderation command failed:", err)
+	return reject, tr("Sorry, this can not be checked right now.")
+}
+
+func moderateWithURL(url, text, kind string) (verdict, string) {
+	body, _ := json.Marshal(struct{ Text, Kind string }{text, kind})
+	client := http.Client{Timeout: moderationTimeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "can not reach moderation service:", err)
+		return reject, tr("Sorry, this can not be checked right now.")
+	}
+	defer resp.Body.Close()
+	var answer struct{ Verdict, Reason string }
+	if resp.StatusCode == http.StatusOK {
+		err = json.NewDecoder(resp.Body).Decode(&answer)
+	} else {
+		err = fmt.Errorf("%s", resp.Status)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "bad moderation answer:", err)
+		return reject, tr("Sorry, this can not be checked right now.")
+	}
+	switch answer.Verdict {
+	case "allow":
+		return allow, ""
+	case "flag":
+		return flagged, answer.Reason
+	}
+	return reject, answer.Reason
+}
+
+// Ops of ops whose texts are not rejected, e.g. received from other copies.
+// Flagged texts are noted so that merge marks their nodes.
+func moderateOps(ops []*op) []*op {
+	var kept []*op
+	for _, o := range ops {
+		ok := true
+		if o.Animal != "" && o.Kind != opDelete {
+			ok, o.animalFlag = moderateOp(o.Animal, false)
+		}
+		if ok && o.Question != "" {
+			ok, o.questionFlag = moderateOp(o.Question, true)
+		}
+		if ok {
+			kept = append(kept, o)
+		}
+	}
+	return kept
+}
+
+// Whether moderation accepts text of op and why it flags it if it does
+func moderateOp(text string, isQuestion bool) (bool, string) {
+	switch v, reason := moderate(text, isQuestion); v {
+	case reject:
+		return false, ""
+	case flagged:
+		if reason == "" {
+			reason = tr("flagged")
+		}
+		return true, reason
+	}
+	return true, ""
+}
+
+// Flag texts of imported tree that moderation flags.  Returns an error
+// naming the first rejected one.
+func moderateTree(root *node) error {
+	var err error
+	visit(root, func(n *node) {
+		if err != nil {
+			return
+		}
+		switch v, reason := moderate(n.text(), !n.isLeaf()); v {
+		case reject:
+			err = fmt.Errorf("%q: %s", n.text(), reason)
+		case flagged:
+			if reason == "" {
+				reason = tr("flagged")
+			}
+			n.Flagged = reason
+		}
+	})
+	return err
+}
+
+// Ask user of command for question until moderation accepts it.  Returns
+// the question and why it is flagged if it is.
+func askModerated(prompt string, args ...interface{}) (string, string) {
+	for {
+		s := ask(prompt, args...)
+		switch v, reason := moderate(s, true); v {
+		case reject:
+			fmt.Println(reason)
+		case flagged:
+			if reason == "" {
+				reason = tr("flagged")
+			}
+			return s, reason
+		default:
+			return s, ""
+		}
+	}
+}
+
+// Ask player for animal name or question until acceptable.  Flagged texts
+// are accepted and remembered so that learnAnimal marks their nodes.
+func (g *game) askContent(prompt string, isQuestion bool) string {
+	for {
+		s := strings.TrimSpace(g.ui.ask(prompt))
+		if s == "" {
+			continue
+		}
+		if reason := g.vet(s, isQuestion); reason != "" {
+			g.ui.tell(reason)
+			continue
+		}
+		return s
+	}
+}
+
+// Check animal name or question against kids mode and moderation.  Returns
+// why s is refused, empty if accepted.  Flagged texts are remembered.
+func (g *game) vet(s string, isQuestion bool) string {
+	if reason := checkContent(s, isQuestion); reason != "" {
+		return reason
+	}
+	v, reason := moderate(s, isQuestion)
+	if reason == "" {
+		reason = tr("flagged")
+	}
+	switch v {
+	case reject:
+		return reason
+	case flagged:
+		if g.flagged == nil {
+			g.flagged = make(map[string]string)
+		}
+		g.flagged[s] = reason
+	}
+	return ""
+}
+
+// Mark node if its text was flagged during this game
+func (g *game) flag(n *node) {
+	if reason, ok := g.flagged[n.text()]; ok {
+		n.Flagged = reason
+	}
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	compositionsOnce sync.Once
+	compositions     map[[2]rune]rune // by starter and combining mark
+)
+
+func loadCompositions() {
+	runes := []rune(compositionTriples)
+	compositions = make(map[[2]rune]rune, len(runes)/3)
+	for i := 0; i+2 < len(runes); i += 3 {
+		compositions[[2]rune{runes[i], runes[i+1]}] = runes[i+2]
+	}
+}
+
+// Hangul syllables are composed algorithmically.
+const (
+	hangulBase   = 0xac00
+	hangulLBase  = 0x1100
+	hangulVBase  = 0x1161
+	hangulTBase  = 0x11a7
+	hangulLCount = 19
+	hangulVCount = 21
+	hangulTCount = 28
+	hangulCount  = 11172
+)
+
+// Rune composed of starter and mark, false if none
+func composePair(starter, mark rune) (rune, bool) {
+	if l := starter - hangulLBase; 0 <= l && l < hangulLCount {
+		if v := mark - hangulVBase; 0 <= v && v < hangulVCount {
+			return hangulBase + (l*hangulVCount+v)*hangulTCount, true
+		}
+	}
+	if s := starter - hangulBase; 0 <= s && s < hangulCount && s%hangulTCount == 0 {
+		if t := mark - hangulTBase; 0 < t && t < hangulTCount {
+			return starter + t, true
+		}
+	}
+	compositionsOnce.Do(loadCompositions)
+	r, ok := compositions[[2]rune{starter, mark}]
+	return r, ok
+}
+
+// Compose letters followed by combining marks into single runes, as NFC
+// does for text typed in the usual order
+func compose(s string) string {
+	if isASCII(s) {
+		return s
+	}
+	var b strings.Builder
+	var prev rune = -1
+	for _, r := range s {
+		if prev >= 0 {
+			if c, ok := composePair(prev, r); ok {
+				prev = c
+				continue
+			}
+			b.WriteRune(prev)
+		}
+		prev = r
+	}
+	if prev >= 0 {
+		b.WriteRune(prev)
+	}
+	return b.String()
+}
+
+// Full case folding of s, which may decompose letters, e.g. ǰ
+func foldCase(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if f, ok := fullFoldings[r]; ok {
+			b.WriteString(f)
+		} else {
+			b.WriteRune(unicode.ToLower(unicode.ToUpper(r)))
+		}
+	}
+	return b.String()
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// Normalized and case-folded form of s for comparing names
+func fold(s string) string {
+	return compose(foldCase(strings.TrimSpace(s)))
+}
+
+// Whether a and b name the same animal
+func sameName(a, b string) bool {
+	return fold(a) == fold(b)
+}
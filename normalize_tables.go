@@ -0,0 +1,296 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Unicode data for normalizing names (see normalize.go), generated from the
+// Unicode 14.0.0 character database.
+
+// Canonical compositions as triples of runes: starter, combining mark and
+// their composition.  Composition exclusions are left out.
+const compositionTriples = "" +
+	"\u0041\u0300\u00c0\u0041\u0301\u00c1\u0041\u0302\u00c2\u0041\u0303\u00c3\u0041\u0308\u00c4\u0041\u030a\u00c5" +
+	"\u0043\u0327\u00c7\u0045\u0300\u00c8\u0045\u0301\u00c9\u0045\u0302\u00ca\u0045\u0308\u00cb\u0049\u0300\u00cc" +
+	"\u0049\u0301\u00cd\u0049\u0302\u00ce\u0049\u0308\u00cf\u004e\u0303\u00d1\u004f\u0300\u00d2\u004f\u0301\u00d3" +
+	"\u004f\u0302\u00d4\u004f\u0303\u00d5\u004f\u0308\u00d6\u0055\u0300\u00d9\u0055\u0301\u00da\u0055\u0302\u00db" +
+	"\u0055\u0308\u00dc\u0059\u0301\u00dd\u0061\u0300\u00e0\u0061\u0301\u00e1\u0061\u0302\u00e2\u0061\u0303\u00e3" +
+	"\u0061\u0308\u00e4\u0061\u030a\u00e5\u0063\u0327\u00e7\u0065\u0300\u00e8\u0065\u0301\u00e9\u0065\u0302\u00ea" +
+	"\u0065\u0308\u00eb\u0069\u0300\u00ec\u0069\u0301\u00ed\u0069\u0302\u00ee\u0069\u0308\u00ef\u006e\u0303\u00f1" +
+	"\u006f\u0300\u00f2\u006f\u0301\u00f3\u006f\u0302\u00f4\u006f\u0303\u00f5\u006f\u0308\u00f6\u0075\u0300\u00f9" +
+	"\u0075\u0301\u00fa\u0075\u0302\u00fb\u0075\u0308\u00fc\u0079\u0301\u00fd\u0079\u0308\u00ff\u0041\u0304\u0100" +
+	"\u0061\u0304\u0101\u0041\u0306\u0102\u0061\u0306\u0103\u0041\u0328\u0104\u0061\u0328\u0105\u0043\u0301\u0106" +
+	"\u0063\u0301\u0107\u0043\u0302\u0108\u0063\u0302\u0109\u0043\u0307\u010a\u0063\u0307\u010b\u0043\u030c\u010c" +
+	"\u0063\u030c\u010d\u0044\u030c\u010e\u0064\u030c\u010f\u0045\u0304\u0112\u0065\u0304\u0113\u0045\u0306\u0114" +
+	"\u0065\u0306\u0115\u0045\u0307\u0116\u0065\u0307\u0117\u0045\u0328\u0118\u0065\u0328\u0119\u0045\u030c\u011a" +
+	"\u0065\u030c\u011b\u0047\u0302\u011c\u0067\u0302\u011d\u0047\u0306\u011e\u0067\u0306\u011f\u0047\u0307\u0120" +
+	"\u0067\u0307\u0121\u0047\u0327\u0122\u0067\u0327\u0123\u0048\u0302\u0124\u0068\u0302\u0125\u0049\u0303\u0128" +
+	"\u0069\u0303\u0129\u0049\u0304\u012a\u0069\u0304\u012b\u0049\u0306\u012c\u0069\u0306\u012d\u0049\u0328\u012e" +
+	"\u0069\u0328\u012f\u0049\u0307\u0130\u004a\u0302\u0134\u006a\u0302\u0135\u004b\u0327\u0136\u006b\u0327\u0137" +
+	"\u004c\u0301\u0139\u006c\u0301\u013a\u004c\u0327\u013b\u006c\u0327\u013c\u004c\u030c\u013d\u006c\u030c\u013e" +
+	"\u004e\u0301\u0143\u006e\u0301\u0144\u004e\u0327\u0145\u006e\u0327\u0146\u004e\u030c\u0147\u006e\u030c\u0148" +
+	"\u004f\u0304\u014c\u006f\u0304\u014d\u004f\u0306\u014e\u006f\u0306\u014f\u004f\u030b\u0150\u006f\u030b\u0151" +
+	"\u0052\u0301\u0154\u0072\u0301\u0155\u0052\u0327\u0156\u0072\u0327\u0157\u0052\u030c\u0158\u0072\u030c\u0159" +
+	"\u0053\u0301\u015a\u0073\u0301\u015b\u0053\u0302\u015c\u0073\u0302\u015d\u0053\u0327\u015e\u0073\u0327\u015f" +
+	"\u0053\u030c\u0160\u0073\u030c\u0161\u0054\u0327\u0162\u0074\u0327\u0163\u0054\u030c\u0164\u0074\u030c\u0165" +
+	"\u0055\u0303\u0168\u0075\u0303\u0169\u0055\u0304\u016a\u0075\u0304\u016b\u0055\u0306\u016c\u0075\u0306\u016d" +
+	"\u0055\u030a\u016e\u0075\u030a\u016f\u0055\u030b\u0170\u0075\u030b\u0171\u0055\u0328\u0172\u0075\u0328\u0173" +
+	"\u0057\u0302\u0174\u0077\u0302\u0175\u0059\u0302\u0176\u0079\u0302\u0177\u0059\u0308\u0178\u005a\u0301\u0179" +
+	"\u007a\u0301\u017a\u005a\u0307\u017b\u007a\u0307\u017c\u005a\u030c\u017d\u007a\u030c\u017e\u004f\u031b\u01a0" +
+	"\u006f\u031b\u01a1\u0055\u031b\u01af\u0075\u031b\u01b0\u0041\u030c\u01cd\u0061\u030c\u01ce\u0049\u030c\u01cf" +
+	"\u0069\u030c\u01d0\u004f\u030c\u01d1\u006f\u030c\u01d2\u0055\u030c\u01d3\u0075\u030c\u01d4\u00dc\u0304\u01d5" +
+	"\u00fc\u0304\u01d6\u00dc\u0301\u01d7\u00fc\u0301\u01d8\u00dc\u030c\u01d9\u00fc\u030c\u01da\u00dc\u0300\u01db" +
+	"\u00fc\u0300\u01dc\u00c4\u0304\u01de\u00e4\u0304\u01df\u0226\u0304\u01e0\u0227\u0304\u01e1\u00c6\u0304\u01e2" +
+	"\u00e6\u0304\u01e3\u0047\u030c\u01e6\u0067\u030c\u01e7\u004b\u030c\u01e8\u006b\u030c\u01e9\u004f\u0328\u01ea" +
+	"\u006f\u0328\u01eb\u01ea\u0304\u01ec\u01eb\u0304\u01ed\u01b7\u030c\u01ee\u0292\u030c\u01ef\u006a\u030c\u01f0" +
+	"\u0047\u0301\u01f4\u0067\u0301\u01f5\u004e\u0300\u01f8\u006e\u0300\u01f9\u00c5\u0301\u01fa\u00e5\u0301\u01fb" +
+	"\u00c6\u0301\u01fc\u00e6\u0301\u01fd\u00d8\u0301\u01fe\u00f8\u0301\u01ff\u0041\u030f\u0200\u0061\u030f\u0201" +
+	"\u0041\u0311\u0202\u0061\u0311\u0203\u0045\u030f\u0204\u0065\u030f\u0205\u0045\u0311\u0206\u0065\u0311\u0207" +
+	"\u0049\u030f\u0208\u0069\u030f\u0209\u0049\u0311\u020a\u0069\u0311\u020b\u004f\u030f\u020c\u006f\u030f\u020d" +
+	"\u004f\u0311\u020e\u006f\u0311\u020f\u0052\u030f\u0210\u0072\u030f\u0211\u0052\u0311\u0212\u0072\u0311\u0213" +
+	"\u0055\u030f\u0214\u0075\u030f\u0215\u0055\u0311\u0216\u0075\u0311\u0217\u0053\u0326\u0218\u0073\u0326\u0219" +
+	"\u0054\u0326\u021a\u0074\u0326\u021b\u0048\u030c\u021e\u0068\u030c\u021f\u0041\u0307\u0226\u0061\u0307\u0227" +
+	"\u0045\u0327\u0228\u0065\u0327\u0229\u00d6\u0304\u022a\u00f6\u0304\u022b\u00d5\u0304\u022c\u00f5\u0304\u022d" +
+	"\u004f\u0307\u022e\u006f\u0307\u022f\u022e\u0304\u0230\u022f\u0304\u0231\u0059\u0304\u0232\u0079\u0304\u0233" +
+	"\u00a8\u0301\u0385\u0391\u0301\u0386\u0395\u0301\u0388\u0397\u0301\u0389\u0399\u0301\u038a\u039f\u0301\u038c" +
+	"\u03a5\u0301\u038e\u03a9\u0301\u038f\u03ca\u0301\u0390\u0399\u0308\u03aa\u03a5\u0308\u03ab\u03b1\u0301\u03ac" +
+	"\u03b5\u0301\u03ad\u03b7\u0301\u03ae\u03b9\u0301\u03af\u03cb\u0301\u03b0\u03b9\u0308\u03ca\u03c5\u0308\u03cb" +
+	"\u03bf\u0301\u03cc\u03c5\u0301\u03cd\u03c9\u0301\u03ce\u03d2\u0301\u03d3\u03d2\u0308\u03d4\u0415\u0300\u0400" +
+	"\u0415\u0308\u0401\u0413\u0301\u0403\u0406\u0308\u0407\u041a\u0301\u040c\u0418\u0300\u040d\u0423\u0306\u040e" +
+	"\u0418\u0306\u0419\u0438\u0306\u0439\u0435\u0300\u0450\u0435\u0308\u0451\u0433\u0301\u0453\u0456\u0308\u0457" +
+	"\u043a\u0301\u045c\u0438\u0300\u045d\u0443\u0306\u045e\u0474\u030f\u0476\u0475\u030f\u0477\u0416\u0306\u04c1" +
+	"\u0436\u0306\u04c2\u0410\u0306\u04d0\u0430\u0306\u04d1\u0410\u0308\u04d2\u0430\u0308\u04d3\u0415\u0306\u04d6" +
+	"\u0435\u0306\u04d7\u04d8\u0308\u04da\u04d9\u0308\u04db\u0416\u0308\u04dc\u0436\u0308\u04dd\u0417\u0308\u04de" +
+	"\u0437\u0308\u04df\u0418\u0304\u04e2\u0438\u0304\u04e3\u0418\u0308\u04e4\u0438\u0308\u04e5\u041e\u0308\u04e6" +
+	"\u043e\u0308\u04e7\u04e8\u0308\u04ea\u04e9\u0308\u04eb\u042d\u0308\u04ec\u044d\u0308\u04ed\u0423\u0304\u04ee" +
+	"\u0443\u0304\u04ef\u0423\u0308\u04f0\u0443\u0308\u04f1\u0423\u030b\u04f2\u0443\u030b\u04f3\u0427\u0308\u04f4" +
+	"\u0447\u0308\u04f5\u042b\u0308\u04f8\u044b\u0308\u04f9\u0627\u0653\u0622\u0627\u0654\u0623\u0648\u0654\u0624" +
+	"\u0627\u0655\u0625\u064a\u0654\u0626\u06d5\u0654\u06c0\u06c1\u0654\u06c2\u06d2\u0654\u06d3\u0928\u093c\u0929" +
+	"\u0930\u093c\u0931\u0933\u093c\u0934\u09c7\u09be\u09cb\u09c7\u09d7\u09cc\u0b47\u0b56\u0b48\u0b47\u0b3e\u0b4b" +
+	"\u0b47\u0b57\u0b4c\u0b92\u0bd7\u0b94\u0bc6\u0bbe\u0bca\u0bc7\u0bbe\u0bcb\u0bc6\u0bd7\u0bcc\u0c46\u0c56\u0c48" +
+	"\u0cbf\u0cd5\u0cc0\u0cc6\u0cd5\u0cc7\u0cc6\u0cd6\u0cc8\u0cc6\u0cc2\u0cca\u0cca\u0cd5\u0ccb\u0d46\u0d3e\u0d4a" +
+	"\u0d47\u0d3e\u0d4b\u0d46\u0d57\u0d4c\u0dd9\u0dca\u0dda\u0dd9\u0dcf\u0ddc\u0ddc\u0dca\u0ddd\u0dd9\u0ddf\u0dde" +
+	"\u1025\u102e\u1026\u1b05\u1b35\u1b06\u1b07\u1b35\u1b08\u1b09\u1b35\u1b0a\u1b0b\u1b35\u1b0c\u1b0d\u1b35\u1b0e" +
+	"\u1b11\u1b35\u1b12\u1b3a\u1b35\u1b3b\u1b3c\u1b35\u1b3d\u1b3e\u1b35\u1b40\u1b3f\u1b35\u1b41\u1b42\u1b35\u1b43" +
+	"\u0041\u0325\u1e00\u0061\u0325\u1e01\u0042\u0307\u1e02\u0062\u0307\u1e03\u0042\u0323\u1e04\u0062\u0323\u1e05" +
+	"\u0042\u0331\u1e06\u0062\u0331\u1e07\u00c7\u0301\u1e08\u00e7\u0301\u1e09\u0044\u0307\u1e0a\u0064\u0307\u1e0b" +
+	"\u0044\u0323\u1e0c\u0064\u0323\u1e0d\u0044\u0331\u1e0e\u0064\u0331\u1e0f\u0044\u0327\u1e10\u0064\u0327\u1e11" +
+	"\u0044\u032d\u1e12\u0064\u032d\u1e13\u0112\u0300\u1e14\u0113\u0300\u1e15\u0112\u0301\u1e16\u0113\u0301\u1e17" +
+	"\u0045\u032d\u1e18\u0065\u032d\u1e19\u0045\u0330\u1e1a\u0065\u0330\u1e1b\u0228\u0306\u1e1c\u0229\u0306\u1e1d" +
+	"\u0046\u0307\u1e1e\u0066\u0307\u1e1f\u0047\u0304\u1e20\u0067\u0304\u1e21\u0048\u0307\u1e22\u0068\u0307\u1e23" +
+	"\u0048\u0323\u1e24\u0068\u0323\u1e25\u0048\u0308\u1e26\u0068\u0308\u1e27\u0048\u0327\u1e28\u0068\u0327\u1e29" +
+	"\u0048\u032e\u1e2a\u0068\u032e\u1e2b\u0049\u0330\u1e2c\u0069\u0330\u1e2d\u00cf\u0301\u1e2e\u00ef\u0301\u1e2f" +
+	"\u004b\u0301\u1e30\u006b\u0301\u1e31\u004b\u0323\u1e32\u006b\u0323\u1e33\u004b\u0331\u1e34\u006b\u0331\u1e35" +
+	"\u004c\u0323\u1e36\u006c\u0323\u1e37\u1e36\u0304\u1e38\u1e37\u0304\u1e39\u004c\u0331\u1e3a\u006c\u0331\u1e3b" +
+	"\u004c\u032d\u1e3c\u006c\u032d\u1e3d\u004d\u0301\u1e3e\u006d\u0301\u1e3f\u004d\u0307\u1e40\u006d\u0307\u1e41" +
+	"\u004d\u0323\u1e42\u006d\u0323\u1e43\u004e\u0307\u1e44\u006e\u0307\u1e45\u004e\u0323\u1e46\u006e\u0323\u1e47" +
+	"\u004e\u0331\u1e48\u006e\u0331\u1e49\u004e\u032d\u1e4a\u006e\u032d\u1e4b\u00d5\u0301\u1e4c\u00f5\u0301\u1e4d" +
+	"\u00d5\u0308\u1e4e\u00f5\u0308\u1e4f\u014c\u0300\u1e50\u014d\u0300\u1e51\u014c\u0301\u1e52\u014d\u0301\u1e53" +
+	"\u0050\u0301\u1e54\u0070\u0301\u1e55\u0050\u0307\u1e56\u0070\u0307\u1e57\u0052\u0307\u1e58\u0072\u0307\u1e59" +
+	"\u0052\u0323\u1e5a\u0072\u0323\u1e5b\u1e5a\u0304\u1e5c\u1e5b\u0304\u1e5d\u0052\u0331\u1e5e\u0072\u0331\u1e5f" +
+	"\u0053\u0307\u1e60\u0073\u0307\u1e61\u0053\u0323\u1e62\u0073\u0323\u1e63\u015a\u0307\u1e64\u015b\u0307\u1e65" +
+	"\u0160\u0307\u1e66\u0161\u0307\u1e67\u1e62\u0307\u1e68\u1e63\u0307\u1e69\u0054\u0307\u1e6a\u0074\u0307\u1e6b" +
+	"\u0054\u0323\u1e6c\u0074\u0323\u1e6d\u0054\u0331\u1e6e\u0074\u0331\u1e6f\u0054\u032d\u1e70\u0074\u032d\u1e71" +
+	"\u0055\u0324\u1e72\u0075\u0324\u1e73\u0055\u0330\u1e74\u0075\u0330\u1e75\u0055\u032d\u1e76\u0075\u032d\u1e77" +
+	"\u0168\u0301\u1e78\u0169\u0301\u1e79\u016a\u0308\u1e7a\u016b\u0308\u1e7b\u0056\u0303\u1e7c\u0076\u0303\u1e7d" +
+	"\u0056\u0323\u1e7e\u0076\u0323\u1e7f\u0057\u0300\u1e80\u0077\u0300\u1e81\u0057\u0301\u1e82\u0077\u0301\u1e83" +
+	"\u0057\u0308\u1e84\u0077\u0308\u1e85\u0057\u0307\u1e86\u0077\u0307\u1e87\u0057\u0323\u1e88\u0077\u0323\u1e89" +
+	"\u0058\u0307\u1e8a\u0078\u0307\u1e8b\u0058\u0308\u1e8c\u0078\u0308\u1e8d\u0059\u0307\u1e8e\u0079\u0307\u1e8f" +
+	"\u005a\u0302\u1e90\u007a\u0302\u1e91\u005a\u0323\u1e92\u007a\u0323\u1e93\u005a\u0331\u1e94\u007a\u0331\u1e95" +
+	"\u0068\u0331\u1e96\u0074\u0308\u1e97\u0077\u030a\u1e98\u0079\u030a\u1e99\u017f\u0307\u1e9b\u0041\u0323\u1ea0" +
+	"\u0061\u0323\u1ea1\u0041\u0309\u1ea2\u0061\u0309\u1ea3\u00c2\u0301\u1ea4\u00e2\u0301\u1ea5\u00c2\u0300\u1ea6" +
+	"\u00e2\u0300\u1ea7\u00c2\u0309\u1ea8\u00e2\u0309\u1ea9\u00c2\u0303\u1eaa\u00e2\u0303\u1eab\u1ea0\u0302\u1eac" +
+	"\u1ea1\u0302\u1ead\u0102\u0301\u1eae\u0103\u0301\u1eaf\u0102\u0300\u1eb0\u0103\u0300\u1eb1\u0102\u0309\u1eb2" +
+	"\u0103\u0309\u1eb3\u0102\u0303\u1eb4\u0103\u0303\u1eb5\u1ea0\u0306\u1eb6\u1ea1\u0306\u1eb7\u0045\u0323\u1eb8" +
+	"\u0065\u0323\u1eb9\u0045\u0309\u1eba\u0065\u0309\u1ebb\u0045\u0303\u1ebc\u0065\u0303\u1ebd\u00ca\u0301\u1ebe" +
+	"\u00ea\u0301\u1ebf\u00ca\u0300\u1ec0\u00ea\u0300\u1ec1\u00ca\u0309\u1ec2\u00ea\u0309\u1ec3\u00ca\u0303\u1ec4" +
+	"\u00ea\u0303\u1ec5\u1eb8\u0302\u1ec6\u1eb9\u0302\u1ec7\u0049\u0309\u1ec8\u0069\u0309\u1ec9\u0049\u0323\u1eca" +
+	"\u0069\u0323\u1ecb\u004f\u0323\u1ecc\u006f\u0323\u1ecd\u004f\u0309\u1ece\u006f\u0309\u1ecf\u00d4\u0301\u1ed0" +
+	"\u00f4\u0301\u1ed1\u00d4\u0300\u1ed2\u00f4\u0300\u1ed3\u00d4\u0309\u1ed4\u00f4\u0309\u1ed5\u00d4\u0303\u1ed6" +
+	"\u00f4\u0303\u1ed7\u1ecc\u0302\u1ed8\u1ecd\u0302\u1ed9\u01a0\u0301\u1eda\u01a1\u0301\u1edb\u01a0\u0300\u1edc" +
+	"\u01a1\u0300\u1edd\u01a0\u0309\u1ede\u01a1\u0309\u1edf\u01a0\u0303\u1ee0\u01a1\u0303\u1ee1\u01a0\u0323\u1ee2" +
+	"\u01a1\u0323\u1ee3\u0055\u0323\u1ee4\u0075\u0323\u1ee5\u0055\u0309\u1ee6\u0075\u0309\u1ee7\u01af\u0301\u1ee8" +
+	"\u01b0\u0301\u1ee9\u01af\u0300\u1eea\u01b0\u0300\u1eeb\u01af\u0309\u1eec\u01b0\u0309\u1eed\u01af\u0303\u1eee" +
+	"\u01b0\u0303\u1eef\u01af\u0323\u1ef0\u01b0\u0323\u1ef1\u0059\u0300\u1ef2\u0079\u0300\u1ef3\u0059\u0323\u1ef4" +
+	"\u0079\u0323\u1ef5\u0059\u0309\u1ef6\u0079\u0309\u1ef7\u0059\u0303\u1ef8\u0079\u0303\u1ef9\u03b1\u0313\u1f00" +
+	"\u03b1\u0314\u1f01\u1f00\u0300\u1f02\u1f01\u0300\u1f03\u1f00\u0301\u1f04\u1f01\u0301\u1f05\u1f00\u0342\u1f06" +
+	"\u1f01\u0342\u1f07\u0391\u0313\u1f08\u0391\u0314\u1f09\u1f08\u0300\u1f0a\u1f09\u0300\u1f0b\u1f08\u0301\u1f0c" +
+	"\u1f09\u0301\u1f0d\u1f08\u0342\u1f0e\u1f09\u0342\u1f0f\u03b5\u0313\u1f10\u03b5\u0314\u1f11\u1f10\u0300\u1f12" +
+	"\u1f11\u0300\u1f13\u1f10\u0301\u1f14\u1f11\u0301\u1f15\u0395\u0313\u1f18\u0395\u0314\u1f19\u1f18\u0300\u1f1a" +
+	"\u1f19\u0300\u1f1b\u1f18\u0301\u1f1c\u1f19\u0301\u1f1d\u03b7\u0313\u1f20\u03b7\u0314\u1f21\u1f20\u0300\u1f22" +
+	"\u1f21\u0300\u1f23\u1f20\u0301\u1f24\u1f21\u0301\u1f25\u1f20\u0342\u1f26\u1f21\u0342\u1f27\u0397\u0313\u1f28" +
+	"\u0397\u0314\u1f29\u1f28\u0300\u1f2a\u1f29\u0300\u1f2b\u1f28\u0301\u1f2c\u1f29\u0301\u1f2d\u1f28\u0342\u1f2e" +
+	"\u1f29\u0342\u1f2f\u03b9\u0313\u1f30\u03b9\u0314\u1f31\u1f30\u0300\u1f32\u1f31\u0300\u1f33\u1f30\u0301\u1f34" +
+	"\u1f31\u0301\u1f35\u1f30\u0342\u1f36\u1f31\u0342\u1f37\u0399\u0313\u1f38\u0399\u0314\u1f39\u1f38\u0300\u1f3a" +
+	"\u1f39\u0300\u1f3b\u1f38\u0301\u1f3c\u1f39\u0301\u1f3d\u1f38\u0342\u1f3e\u1f39\u0342\u1f3f\u03bf\u0313\u1f40" +
+	"\u03bf\u0314\u1f41\u1f40\u0300\u1f42\u1f41\u0300\u1f43\u1f40\u0301\u1f44\u1f41\u0301\u1f45\u039f\u0313\u1f48" +
+	"\u039f\u0314\u1f49\u1f48\u0300\u1f4a\u1f49\u0300\u1f4b\u1f48\u0301\u1f4c\u1f49\u0301\u1f4d\u03c5\u0313\u1f50" +
+	"\u03c5\u0314\u1f51\u1f50\u0300\u1f52\u1f51\u0300\u1f53\u1f50\u0301\u1f54\u1f51\u0301\u1f55\u1f50\u0342\u1f56" +
+	"\u1f51\u0342\u1f57\u03a5\u0314\u1f59\u1f59\u0300\u1f5b\u1f59\u0301\u1f5d\u1f59\u0342\u1f5f\u03c9\u0313\u1f60" +
+	"\u03c9\u0314\u1f61\u1f60\u0300\u1f62\u1f61\u0300\u1f63\u1f60\u0301\u1f64\u1f61\u0301\u1f65\u1f60\u0342\u1f66" +
+	"\u1f61\u0342\u1f67\u03a9\u0313\u1f68\u03a9\u0314\u1f69\u1f68\u0300\u1f6a\u1f69\u0300\u1f6b\u1f68\u0301\u1f6c" +
+	"\u1f69\u0301\u1f6d\u1f68\u0342\u1f6e\u1f69\u0342\u1f6f\u03b1\u0300\u1f70\u03b5\u0300\u1f72\u03b7\u0300\u1f74" +
+	"\u03b9\u0300\u1f76\u03bf\u0300\u1f78\u03c5\u0300\u1f7a\u03c9\u0300\u1f7c\u1f00\u0345\u1f80\u1f01\u0345\u1f81" +
+	"\u1f02\u0345\u1f82\u1f03\u0345\u1f83\u1f04\u0345\u1f84\u1f05\u0345\u1f85\u1f06\u0345\u1f86\u1f07\u0345\u1f87" +
+	"\u1f08\u0345\u1f88\u1f09\u0345\u1f89\u1f0a\u0345\u1f8a\u1f0b\u0345\u1f8b\u1f0c\u0345\u1f8c\u1f0d\u0345\u1f8d" +
+	"\u1f0e\u0345\u1f8e\u1f0f\u0345\u1f8f\u1f20\u0345\u1f90\u1f21\u0345\u1f91\u1f22\u0345\u1f92\u1f23\u0345\u1f93" +
+	"\u1f24\u0345\u1f94\u1f25\u0345\u1f95\u1f26\u0345\u1f96\u1f27\u0345\u1f97\u1f28\u0345\u1f98\u1f29\u0345\u1f99" +
+	"\u1f2a\u0345\u1f9a\u1f2b\u0345\u1f9b\u1f2c\u0345\u1f9c\u1f2d\u0345\u1f9d\u1f2e\u0345\u1f9e\u1f2f\u0345\u1f9f" +
+	"\u1f60\u0345\u1fa0\u1f61\u0345\u1fa1\u1f62\u0345\u1fa2\u1f63\u0345\u1fa3\u1f64\u0345\u1fa4\u1f65\u0345\u1fa5" +
+	"\u1f66\u0345\u1fa6\u1f67\u0345\u1fa7\u1f68\u0345\u1fa8\u1f69\u0345\u1fa9\u1f6a\u0345\u1faa\u1f6b\u0345\u1fab" +
+	"\u1f6c\u0345\u1fac\u1f6d\u0345\u1fad\u1f6e\u0345\u1fae\u1f6f\u0345\u1faf\u03b1\u0306\u1fb0\u03b1\u0304\u1fb1" +
+	"\u1f70\u0345\u1fb2\u03b1\u0345\u1fb3\u03ac\u0345\u1fb4\u03b1\u0342\u1fb6\u1fb6\u0345\u1fb7\u0391\u0306\u1fb8" +
+	"\u0391\u0304\u1fb9\u0391\u0300\u1fba\u0391\u0345\u1fbc\u00a8\u0342\u1fc1\u1f74\u0345\u1fc2\u03b7\u0345\u1fc3" +
+	"\u03ae\u0345\u1fc4\u03b7\u0342\u1fc6\u1fc6\u0345\u1fc7\u0395\u0300\u1fc8\u0397\u0300\u1fca\u0397\u0345\u1fcc" +
+	"\u1fbf\u0300\u1fcd\u1fbf\u0301\u1fce\u1fbf\u0342\u1fcf\u03b9\u0306\u1fd0\u03b9\u0304\u1fd1\u03ca\u0300\u1fd2" +
+	"\u03b9\u0342\u1fd6\u03ca\u0342\u1fd7\u0399\u0306\u1fd8\u0399\u0304\u1fd9\u0399\u0300\u1fda\u1ffe\u0300\u1fdd" +
+	"\u1ffe\u0301\u1fde\u1ffe\u0342\u1fdf\u03c5\u0306\u1fe0\u03c5\u0304\u1fe1\u03cb\u0300\u1fe2\u03c1\u0313\u1fe4" +
+	"\u03c1\u0314\u1fe5\u03c5\u0342\u1fe6\u03cb\u0342\u1fe7\u03a5\u0306\u1fe8\u03a5\u0304\u1fe9\u03a5\u0300\u1fea" +
+	"\u03a1\u0314\u1fec\u00a8\u0300\u1fed\u1f7c\u0345\u1ff2\u03c9\u0345\u1ff3\u03ce\u0345\u1ff4\u03c9\u0342\u1ff6" +
+	"\u1ff6\u0345\u1ff7\u039f\u0300\u1ff8\u03a9\u0300\u1ffa\u03a9\u0345\u1ffc\u2190\u0338\u219a\u2192\u0338\u219b" +
+	"\u2194\u0338\u21ae\u21d0\u0338\u21cd\u21d4\u0338\u21ce\u21d2\u0338\u21cf\u2203\u0338\u2204\u2208\u0338\u2209" +
+	"\u220b\u0338\u220c\u2223\u0338\u2224\u2225\u0338\u2226\u223c\u0338\u2241\u2243\u0338\u2244\u2245\u0338\u2247" +
+	"\u2248\u0338\u2249\u003d\u0338\u2260\u2261\u0338\u2262\u224d\u0338\u226d\u003c\u0338\u226e\u003e\u0338\u226f" +
+	"\u2264\u0338\u2270\u2265\u0338\u2271\u2272\u0338\u2274\u2273\u0338\u2275\u2276\u0338\u2278\u2277\u0338\u2279" +
+	"\u227a\u0338\u2280\u227b\u0338\u2281\u2282\u0338\u2284\u2283\u0338\u2285\u2286\u0338\u2288\u2287\u0338\u2289" +
+	"\u22a2\u0338\u22ac\u22a8\u0338\u22ad\u22a9\u0338\u22ae\u22ab\u0338\u22af\u227c\u0338\u22e0\u227d\u0338\u22e1" +
+	"\u2291\u0338\u22e2\u2292\u0338\u22e3\u22b2\u0338\u22ea\u22b3\u0338\u22eb\u22b4\u0338\u22ec\u22b5\u0338\u22ed" +
+	"\u304b\u3099\u304c\u304d\u3099\u304e\u304f\u3099\u3050\u3051\u3099\u3052\u3053\u3099\u3054\u3055\u3099\u3056" +
+	"\u3057\u3099\u3058\u3059\u3099\u305a\u305b\u3099\u305c\u305d\u3099\u305e\u305f\u3099\u3060\u3061\u3099\u3062" +
+	"\u3064\u3099\u3065\u3066\u3099\u3067\u3068\u3099\u3069\u306f\u3099\u3070\u306f\u309a\u3071\u3072\u3099\u3073" +
+	"\u3072\u309a\u3074\u3075\u3099\u3076\u3075\u309a\u3077\u3078\u3099\u3079\u3078\u309a\u307a\u307b\u3099\u307c" +
+	"\u307b\u309a\u307d\u3046\u3099\u3094\u309d\u3099\u309e\u30ab\u3099\u30ac\u30ad\u3099\u30ae\u30af\u3099\u30b0" +
+	"\u30b1\u3099\u30b2\u30b3\u3099\u30b4\u30b5\u3099\u30b6\u30b7\u3099\u30b8\u30b9\u3099\u30ba\u30bb\u3099\u30bc" +
+	"\u30bd\u3099\u30be\u30bf\u3099\u30c0\u30c1\u3099\u30c2\u30c4\u3099\u30c5\u30c6\u3099\u30c7\u30c8\u3099\u30c9" +
+	"\u30cf\u3099\u30d0\u30cf\u309a\u30d1\u30d2\u3099\u30d3\u30d2\u309a\u30d4\u30d5\u3099\u30d6\u30d5\u309a\u30d7" +
+	"\u30d8\u3099\u30d9\u30d8\u309a\u30da\u30db\u3099\u30dc\u30db\u309a\u30dd\u30a6\u3099\u30f4\u30ef\u3099\u30f7" +
+	"\u30f0\u3099\u30f8\u30f1\u3099\u30f9\u30f2\u3099\u30fa\u30fd\u3099\u30fe\U00011099\U000110ba\U0001109a\U0001109b\U000110ba\U0001109c" +
+	"\U000110a5\U000110ba\U000110ab\U00011131\U00011127\U0001112e\U00011132\U00011127\U0001112f\U00011347\U0001133e\U0001134b\U00011347\U00011357\U0001134c\U000114b9\U000114ba\U000114bb" +
+	"\U000114b9\U000114b0\U000114bc\U000114b9\U000114bd\U000114be\U000115b8\U000115af\U000115ba\U000115b9\U000115af\U000115bb\U00011935\U00011930\U00011938"
+
+// Case foldings of runes into several runes, the others folding into the
+// lower case of their upper case
+var fullFoldings = map[rune]string{
+	'\u00df': "\u0073\u0073",
+	'\u0130': "\u0069\u0307",
+	'\u0149': "\u02bc\u006e",
+	'\u01f0': "\u006a\u030c",
+	'\u0390': "\u03b9\u0308\u0301",
+	'\u03b0': "\u03c5\u0308\u0301",
+	'\u0587': "\u0565\u0582",
+	'\u1e96': "\u0068\u0331",
+	'\u1e97': "\u0074\u0308",
+	'\u1e98': "\u0077\u030a",
+	'\u1e99': "\u0079\u030a",
+	'\u1e9a': "\u0061\u02be",
+	'\u1e9e': "\u0073\u0073",
+	'\u1f50': "\u03c5\u0313",
+	'\u1f52': "\u03c5\u0313\u0300",
+	'\u1f54': "\u03c5\u0313\u0301",
+	'\u1f56': "\u03c5\u0313\u0342",
+	'\u1f80': "\u1f00\u03b9",
+	'\u1f81': "\u1f01\u03b9",
+	'\u1f82': "\u1f02\u03b9",
+	'\u1f83': "\u1f03\u03b9",
+	'\u1f84': "\u1f04\u03b9",
+	'\u1f85': "\u1f05\u03b9",
+	'\u1f86': "\u1f06\u03b9",
+	'\u1f87': "\u1f07\u03b9",
+	'\u1f88': "\u1f00\u03b9",
+	'\u1f89': "\u1f01\u03b9",
+	'\u1f8a': "\u1f02\u03b9",
+	'\u1f8b': "\u1f03\u03b9",
+	'\u1f8c': "\u1f04\u03b9",
+	'\u1f8d': "\u1f05\u03b9",
+	'\u1f8e': "\u1f06\u03b9",
+	'\u1f8f': "\u1f07\u03b9",
+	'\u1f90': "\u1f20\u03b9",
+	'\u1f91': "\u1f21\u03b9",
+	'\u1f92': "\u1f22\u03b9",
+	'\u1f93': "\u1f23\u03b9",
+	'\u1f94': "\u1f24\u03b9",
+	'\u1f95': "\u1f25\u03b9",
+	'\u1f96': "\u1f26\u03b9",
+	'\u1f97': "\u1f27\u03b9",
+	'\u1f98': "\u1f20\u03b9",
+	'\u1f99': "\u1f21\u03b9",
+	'\u1f9a': "\u1f22\u03b9",
+	'\u1f9b': "\u1f23\u03b9",
+	'\u1f9c': "\u1f24\u03b9",
+	'\u1f9d': "\u1f25\u03b9",
+	'\u1f9e': "\u1f26\u03b9",
+	'\u1f9f': "\u1f27\u03b9",
+	'\u1fa0': "\u1f60\u03b9",
+	'\u1fa1': "\u1f61\u03b9",
+	'\u1fa2': "\u1f62\u03b9",
+	'\u1fa3': "\u1f63\u03b9",
+	'\u1fa4': "\u1f64\u03b9",
+	'\u1fa5': "\u1f65\u03b9",
+	'\u1fa6': "\u1f66\u03b9",
+	'\u1fa7': "\u1f67\u03b9",
+	'\u1fa8': "\u1f60\u03b9",
+	'\u1fa9': "\u1f61\u03b9",
+	'\u1faa': "\u1f62\u03b9",
+	'\u1fab': "\u1f63\u03b9",
+	'\u1fac': "\u1f64\u03b9",
+	'\u1fad': "\u1f65\u03b9",
+	'\u1fae': "\u1f66\u03b9",
+	'\u1faf': "\u1f67\u03b9",
+	'\u1fb2': "\u1f70\u03b9",
+	'\u1fb3': "\u03b1\u03b9",
+	'\u1fb4': "\u03ac\u03b9",
+	'\u1fb6': "\u03b1\u0342",
+	'\u1fb7': "\u03b1\u0342\u03b9",
+	'\u1fbc': "\u03b1\u03b9",
+	'\u1fc2': "\u1f74\u03b9",
+	'\u1fc3': "\u03b7\u03b9",
+	'\u1fc4': "\u03ae\u03b9",
+	'\u1fc6': "\u03b7\u0342",
+	'\u1fc7': "\u03b7\u0342\u03b9",
+	'\u1fcc': "\u03b7\u03b9",
+	'\u1fd2': "\u03b9\u0308\u0300",
+	'\u1fd3': "\u03b9\u0308\u0301",
+	'\u1fd6': "\u03b9\u0342",
+	'\u1fd7': "\u03b9\u0308\u0342",
+	'\u1fe2': "\u03c5\u0308\u0300",
+	'\u1fe3': "\u03c5\u0308\u0301",
+	'\u1fe4': "\u03c1\u0313",
+	'\u1fe6': "\u03c5\u0342",
+	'\u1fe7': "\u03c5\u0308\u0342",
+	'\u1ff2': "\u1f7c\u03b9",
+	'\u1ff3': "\u03c9\u03b9",
+	'\u1ff4': "\u03ce\u03b9",
+	'\u1ff6': "\u03c9\u0342",
+	'\u1ff7': "\u03c9\u0342\u03b9",
+	'\u1ffc': "\u03c9\u03b9",
+	'\ufb00': "\u0066\u0066",
+	'\ufb01': "\u0066\u0069",
+	'\ufb02': "\u0066\u006c",
+	'\ufb03': "\u0066\u0066\u0069",
+	'\ufb04': "\u0066\u0066\u006c",
+	'\ufb05': "\u0073\u0074",
+	'\ufb06': "\u0073\u0074",
+	'\ufb13': "\u0574\u0576",
+	'\ufb14': "\u0574\u0565",
+	'\ufb15': "\u0574\u056b",
+	'\ufb16': "\u057e\u0576",
+	'\ufb17': "\u0574\u056d",
+}
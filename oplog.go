@@ -0,0 +1,383 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Learning events are recorded in an operation-based CRDT so that copies of
+// a database modified independently (e.g. on laptops playing offline) can be
+// merged without conflicts.
+//
+// Every op has a unique (Replica, Seq) identity and a Lamport timestamp.  The
+// tree is obtained by replaying all known ops on a shared base tree in
+// timestamp order, so any two copies that have seen the same set of ops hold
+// the same tree whatever order they received them in.
+//
+// A learn op splits the node it targets, usually a leaf.  The displaced
+// content moves to a new node that keeps the target ID, so that concurrent
+// ops splitting the same node apply one after the other: the later one
+// refines the branch created by the earlier one.  Curation ops (see
+// curate.go) edit or remove the node they target.
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	opLearn  = "learn"
+	opDelete = "delete"
+	opRename = "rename"
+	opEdit   = "edit"
+)
+
+// Change of tree
+type op struct {
+	Kind    string
+	Replica string // copy that produced the op
+	Seq     uint64 // per-replica counter
+	Clock   uint64 // Lamport timestamp
+
+	// ID of the node turned into a question node, removed, renamed or
+	// whose question is rephrased
+	Target string
+
+	// New animal and question distinguishing it from the target subtree,
+	// removed animal or new name, new question
+	Animal   string
+	Question string
+	IsYes    bool
+
+	// Why moderation flagged the animal or question of op received from
+	// another copy, marked on their nodes once merged.  Not saved.
+	animalFlag, questionFlag string
+}
+
+func (o *op) id() string {
+	return fmt.Sprintf("%s.%d", o.Replica, o.Seq)
+}
+
+// Total order used when replaying
+func (o *op) before(p *op) bool {
+	if o.Clock != p.Clock {
+		return o.Clock < p.Clock
+	}
+	if o.Replica != p.Replica {
+		return o.Replica < p.Replica
+	}
+	return o.Seq < p.Seq
+}
+
+// Identifier of this copy, generated on first use and kept in the user
+// configuration directory.
+var replica string
+
+func localReplica() string {
+	if replica != "" {
+		return replica
+	}
+	var file string
+	if dir, err := os.UserConfigDir(); err == nil {
+		file = filepath.Join(dir, "ask-and-learn", "replica")
+		if content, err := ioutil.ReadFile(file); err == nil {
+			replica = string(bytes.TrimSpace(content))
+		}
+	}
+	if replica == "" {
+		buf := make([]byte, 8)
+		if _, err := rand.Read(buf); err != nil {
+			log.Panic("can not generate replica id: ", err)
+		}
+		replica = hex.EncodeToString(buf)
+		if file != "" {
+			// Failing to persist only costs a fresh ID next time.
+			if os.MkdirAll(filepath.Dir(file), 0700) == nil {
+				ioutil.WriteFile(file, []byte(replica+"\n"), 0600)
+			}
+		}
+	}
+	return replica
+}
+
+// Record that node n has been split by question into leaf and the former
+// content of n, and update the tree accordingly.
+func (d *database) learn(n *node, leaf *node, question string, isYesLeaf bool) {
+	o := &op{
+		Kind:     opLearn,
+		Target:   n.ID,
+		Animal:   leaf.Animal,
+		Question: question,
+		IsYes:    isYesLeaf,
+	}
+	d.record(o)
+	o.apply(n, leaf)
+	if !n.isDescendantOf(d.Root) {
+		// A merge replaced the tree while n was being explored.
+		d.replay()
+	}
+}
+
+// Stamp op produced by this copy and add it to the log
+func (d *database) record(o *op) {
+	me := localReplica()
+	if d.personal != nil {
+		me = overlayReplica(me)
+	}
+	o.Replica = me
+	o.Seq = d.lastSeq(me) + 1
+	o.Clock = d.Clock + 1
+	d.Clock = o.Clock
+	d.Ops = append(d.Ops, o)
+	if d.personal != nil {
+		d.personal[o.id()] = true
+	}
+}
+
+func (d *database) lastSeq(replica string) (seq uint64) {
+	for _, o := range d.Ops {
+		if o.Replica == replica && o.Seq > seq {
+			seq = o.Seq
+		}
+	}
+	return
+}
+
+// Split node n according to o.  leaf receives the new animal and the former
+// content of n keeps its ID.
+func (o *op) apply(n *node, leaf *node) {
+	id := o.id()
+	mutateIntoQuestionNode(n, o.Question, leaf, o.IsYes)
+	n.ID = id + "q"
+	leaf.ID = id
+}
+
+// Rebuild Root from Base and Ops.  Ops whose target vanished are ignored.
+func (d *database) replay() {
+	sort.SliceStable(d.Ops, func(i, j int) bool { return d.Ops[i].before(d.Ops[j]) })
+	root := cloneTree(d.Base)
+	index := make(map[string]*node)
+	indexTree(root, index)
+	for _, o := range d.Ops {
+		n := index[o.Target]
+		if n == nil {
+			continue
+		}
+		switch o.Kind {
+		case opLearn:
+			leaf := &node{Animal: o.Animal}
+			o.apply(n, leaf)
+			indexTree(n, index)
+		case opDelete:
+			removeLeaf(&root, n, index)
+		case opRename:
+			n.Animal = o.Animal
+		case opEdit:
+			n.Question = o.Question
+		}
+	}
+	copyStats(d.Root, index)
+	d.Root = root
+}
+
+// Make current tree the new base, for changes that can not be expressed as
+// ops.  Copies of the database made before can not be merged anymore.
+func (d *database) rebase() {
+	clearIDs(d.Root)
+	assignIDs(d.Root, "r")
+	d.Base = cloneTree(d.Root)
+	d.Ops = nil
+}
+
+// Whether d merged ops of other copies, which can not merge with it anymore
+// once it is rebased
+func (d *database) hasPeers() bool {
+	me := localReplica()
+	for _, o := range d.Ops {
+		if o.Replica != me && o.Replica != overlayReplica(me) {
+			return true
+		}
+	}
+	return false
+}
+
+// Ask user of command to confirm a change rebasing d if it has peers
+func confirmRebase(d *database) bool {
+	if !d.hasPeers() {
+		return true
+	}
+	if stdin == nil {
+		stdin = bufio.NewReader(os.Stdin)
+	}
+	return askYesNo("%s", "Copies of the database it merged with will no longer merge with it. Continue?")
+}
+
+func clearIDs(n *node) {
+	if n == nil {
+		return
+	}
+	n.ID = ""
+	clearIDs(n.No)
+	clearIDs(n.Yes)
+}
+
+func indexTree(n *node, index map[string]*node) {
+	if n == nil {
+		return
+	}
+	index[n.ID] = n
+	indexTree(n.No, index)
+	indexTree(n.Yes, index)
+}
+
+// Add ops unknown to d and rebuild the tree.  Both databases must have grown
+// from the same base.  Returns the number of ops added.
+func (d *database) merge(ops []*op) int {
+	known := d.knownOps()
+	var merged []*op
+	nodes := nodeCount(d.Root)
+	for _, o := range ops {
+		if known[o.id()] {
+			continue
+		}
+		known[o.id()] = true
+		if o.Kind == opLearn {
+			if rejectsMerge(nodes) {
+				continue
+			}
+			nodes += 2
+		}
+		d.Ops = append(d.Ops, o)
+		if o.Clock > d.Clock {
+			d.Clock = o.Clock
+		}
+		merged = append(merged, o)
+	}
+	if len(merged) > 0 {
+		d.replay()
+		d.flagMerged(merged)
+		d.prune(nil)
+	}
+	return len(merged)
+}
+
+// Mark nodes of merged ops whose texts moderation flagged
+func (d *database) flagMerged(ops []*op) {
+	index := make(map[string]*node)
+	indexTree(d.Root, index)
+	flag := func(id, why string) {
+		if n := index[id]; n != nil && why != "" {
+			n.Flagged = why
+		}
+	}
+	for _, o := range ops {
+		switch o.Kind {
+		case opLearn:
+			flag(o.id(), o.animalFlag)
+			flag(o.id()+"q", o.questionFlag)
+		case opRename:
+			flag(o.Target, o.animalFlag)
+		case opEdit:
+			flag(o.Target, o.questionFlag)
+		}
+	}
+}
+
+// Lamport timestamps received ops may have beyond the clock of the database
+// and the number of ops received
+const maxClockLead = 1 << 16
+
+// Report why ops received from another copy can not be merged: new ops
+// claiming to come from this copy, which would collide with the ones it
+// records, or clocks too far ahead to have been reached by ops of the batch.
+func (d *database) checkIncoming(ops []*op) error {
+	me := localReplica()
+	known := d.knownOps()
+	for _, o := range ops {
+		switch {
+		case known[o.id()]:
+		case o.Replica == me || o.Replica == overlayReplica(me):
+			return fmt.Errorf("op %s claims to come from this copy", o.id())
+		case o.Clock > d.Clock+uint64(len(ops))+maxClockLead:
+			return fmt.Errorf("op %s has clock %d, too far ahead of %d", o.id(), o.Clock, d.Clock)
+		}
+	}
+	return nil
+}
+
+// IDs of ops of d
+func (d *database) knownOps() map[string]bool {
+	known := make(map[string]bool)
+	for _, o := range d.Ops {
+		known[o.id()] = true
+	}
+	return known
+}
+
+// Report whether d and other can be merged
+func (d *database) sameBase(other *database) bool {
+	a, errA := json.Marshal(d.Base)
+	b, errB := json.Marshal(other.Base)
+	return errA == nil && errB == nil && bytes.Equal(a, b)
+}
+
+func init() {
+	commands = append(commands, &command{
+		Name:  "merge",
+		Args:  "database-file other-database-file...",
+		Short: "merge animals learned by other copies of a database",
+		Run:   runMerge,
+	})
+}
+
+func runMerge(cmd *command, args []string) {
+	if len(args) < 2 {
+		cmd.fail("databases expected")
+	}
+	d, err := loadDatabase(args[0])
+	if err != nil {
+		log.Panic("can not load db: ", err)
+	}
+	for _, path := range args[1:] {
+		other, err := loadDatabase(path)
+		if err != nil {
+			log.Panic("can not load db: ", err)
+		}
+		if !d.sameBase(other) {
+			fmt.Fprintf(os.Stderr, "%s: does not derive from the same database as %s\n", path, args[0])
+			os.Exit(1)
+		}
+		n := d.merge(moderateOps(other.Ops))
+		fmt.Printf("%s: %d new change(s)\n", path, n)
+	}
+	err = d.save(args[0])
+	if err != nil {
+		log.Panic("can not save db: ", err)
+	}
+}
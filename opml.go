@@ -0,0 +1,217 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// OPML outlines, the interchange format of outliners and mind-mapping tools.
+// Each question is an outline whose two children are prefixed by the answer
+// leading to them, e.g. "Yes: Does it swim?", and animals are outlines
+// without children, their description as note.  On import, children lacking
+// a prefix are taken yes first, and children made only of the answer
+// holding a single outline, as mind maps tend to draw them, are accepted.
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+)
+
+func init() {
+	commands = append(commands, &command{
+		Name:  "opml",
+		Args:  "database-file",
+		Short: "print tree as OPML outline",
+		Run:   runOPML,
+	})
+
+	cmd := &command{
+		Name:  "import-opml",
+		Args:  "database-file opml-file",
+		Short: "create database from OPML outline",
+		Run:   runImportOPML,
+	}
+	opmlCategory = cmd.Flag.String("category", "", "kind of things to guess (default: outline title if known)")
+	opmlForce = cmd.Flag.Bool("f", false, "overwrite existing file")
+	commands = append(commands, cmd)
+}
+
+var (
+	opmlCategory *string
+	opmlForce    *bool
+)
+
+type opmlDocument struct {
+	XMLName  xml.Name   `xml:"opml"`
+	Version  string     `xml:"version,attr"`
+	Title    string     `xml:"head>title"`
+	Outlines []*outline `xml:"body>outline"`
+}
+
+type outline struct {
+	Text     string     `xml:"text,attr"`
+	Note     string     `xml:"_note,attr,omitempty"`
+	Outlines []*outline `xml:"outline"`
+}
+
+const (
+	opmlYes = "Yes: "
+	opmlNo  = "No: "
+)
+
+// Outline of subtree n, text prefixed by answer
+func toOutline(n *node, answer string) *outline {
+	if n.isLeaf() {
+		return &outline{Text: answer + n.Animal, Note: n.Description}
+	}
+	return &outline{
+		Text:     answer + n.Question,
+		Outlines: []*outline{toOutline(n.Yes, opmlYes), toOutline(n.No, opmlNo)},
+	}
+}
+
+// Answer prefixing text if any and the rest of text
+func splitAnswer(text string) (answer string, rest string) {
+	text = cleanText(text)
+	i := strings.Index(text, ":")
+	a := strings.ToLower(text)
+	if i >= 0 {
+		a = strings.TrimSpace(a[:i])
+		rest = strings.TrimSpace(text[i+1:])
+	}
+	switch a {
+	case "yes", "y":
+		return "yes", rest
+	case "no", "n":
+		return "no", rest
+	}
+	return "", text
+}
+
+// Subtree described by outline o whose text lacks its answer
+func fromOutline(o *outline, text string) (*node, error) {
+	if len(o.Outlines) == 0 {
+		if text == "" {
+			return nil, fmt.Errorf("empty outline")
+		}
+		return &node{Animal: text, Description: cleanText(o.Note)}, nil
+	}
+	if len(o.Outlines) != 2 {
+		return nil, fmt.Errorf("%q: 2 answers expected, got %d", text, len(o.Outlines))
+	}
+	n := &node{Question: cleanQuestion(text)}
+	if n.Question == "" {
+		return nil, fmt.Errorf("outline with answers but no question")
+	}
+	yes, no := o.Outlines[0], o.Outlines[1]
+	yesAnswer, yesText := splitAnswer(yes.Text)
+	noAnswer, noText := splitAnswer(no.Text)
+	if yesAnswer == "no" || noAnswer == "yes" {
+		yes, no = no, yes
+		yesText, noText = noText, yesText
+	}
+	var err error
+	if n.Yes, err = fromAnswer(yes, yesText); err != nil {
+		return nil, err
+	}
+	if n.No, err = fromAnswer(no, noText); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// Subtree of answer outline o, possibly a bare answer holding it
+func fromAnswer(o *outline, text string) (*node, error) {
+	if text == "" && len(o.Outlines) == 1 {
+		child := o.Outlines[0]
+		return fromOutline(child, cleanText(child.Text))
+	}
+	return fromOutline(o, text)
+}
+
+func runOPML(cmd *command, args []string) {
+	if len(args) != 1 {
+		cmd.fail("database expected")
+	}
+	d, err := loadDatabase(args[0])
+	if err != nil {
+		log.Panic("can not load db: ", err)
+	}
+	doc := &opmlDocument{Version: "2.0", Title: d.category(), Outlines: []*outline{toOutline(d.Root, "")}}
+	content, err := xml.MarshalIndent(doc, "", "    ")
+	if err != nil {
+		log.Panic("can not encode outline: ", err)
+	}
+	fmt.Printf("%s%s\n", xml.Header, content)
+}
+
+func runImportOPML(cmd *command, args []string) {
+	if len(args) != 2 {
+		cmd.fail("database and outline expected")
+	}
+	if _, err := os.Stat(args[0]); err == nil && !*opmlForce {
+		fmt.Fprintf(os.Stderr, "%s already exists (use -f to overwrite)\n", args[0])
+		os.Exit(1)
+	}
+	content, err := ioutil.ReadFile(args[1])
+	if err != nil {
+		log.Panic("can not load outline: ", err)
+	}
+	doc := new(opmlDocument)
+	if err := xml.Unmarshal(content, doc); err != nil {
+		log.Panic("can not parse outline: ", err)
+	}
+	category := *opmlCategory
+	if category == "" {
+		category = defaultCategory
+		if _, ok := categories[strings.TrimSpace(doc.Title)]; ok {
+			category = strings.TrimSpace(doc.Title)
+		}
+	}
+	if _, ok := categories[category]; !ok {
+		cmd.fail("unknown category %q", category)
+	}
+	if len(doc.Outlines) != 1 {
+		fmt.Fprintf(os.Stderr, "%s: one top-level outline expected, got %d\n", args[1], len(doc.Outlines))
+		os.Exit(1)
+	}
+	root, err := fromOutline(doc.Outlines[0], cleanText(doc.Outlines[0].Text))
+	if err == nil {
+		err = moderateTree(root)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", args[1], err)
+		os.Exit(1)
+	}
+
+	d := newDatabase(root)
+	if category != defaultCategory {
+		d.Category = category
+	}
+	fmt.Printf("%d animals, %d questions\n", root.leafCount(), len(questionNodes(root)))
+	err = d.save(args[0])
+	if err != nil {
+		log.Panic("can not save db: ", err)
+	}
+}
@@ -0,0 +1,141 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+func init() {
+	cmd := &command{
+		Name:  "llm-play",
+		Args:  "database-file",
+		Short: "let the language model play games against database and report outcome",
+		Run:   runLLMPlay,
+	}
+	llmGames = cmd.Flag.Int("games", 10, "number of games")
+	llmLearn = cmd.Flag.Bool("learn", false, "save animals taught by the model")
+	llmVerbose = cmd.Flag.Bool("v", false, "print conversations")
+	commands = append(commands, cmd)
+}
+
+var (
+	llmGames   *int
+	llmLearn   *bool
+	llmVerbose *bool
+)
+
+// Console answering the program with the language model, which plays the
+// role of the human player
+type llmPlayer struct {
+	messages []chatMessage
+}
+
+func newLLMPlayer(category, animal string) *llmPlayer {
+	return &llmPlayer{messages: []chatMessage{{"system", fmt.Sprintf(
+		"You play a guessing game as the player and secretly picked the %s %q. "+
+			"Answer yes-or-no questions truthfully with only yes or no. "+
+			"When asked for a name, reply with the name only. "+
+			"When asked for a question, reply with one short yes-or-no question only.",
+		category, animal) + languageHint()}}}
+}
+
+func (p *llmPlayer) ask(prompt string) string {
+	p.messages = append(p.messages, chatMessage{"user", prompt})
+	reply, err := chat(p.messages)
+	if err != nil {
+		log.Panic("can not chat with model: ", err)
+	}
+	p.messages = append(p.messages, chatMessage{"assistant", reply})
+	if *llmVerbose {
+		fmt.Printf("    %s %s\n", prompt, reply)
+	}
+	return strings.Trim(reply, "\". ")
+}
+
+func (p *llmPlayer) askYesNo(prompt string) bool {
+	for i := 0; i < 2; i++ {
+		if yes, ok := parseYesNo(p.ask(prompt + " (yes or no)")); ok {
+			return yes
+		}
+	}
+	return false
+}
+
+func (p *llmPlayer) tell(msg string) {
+	p.messages = append(p.messages, chatMessage{"user", msg})
+}
+
+// Animal picked by the model, different from those of previous games
+func pickAnimal(category string, picked []string) string {
+	prompt := fmt.Sprintf("Pick a %s for a guessing game and reply with its name only.", category)
+	if len(picked) > 0 {
+		prompt += " Do not pick any of: " + strings.Join(picked, ", ") + "."
+	}
+	reply, err := chat([]chatMessage{{"user", prompt}})
+	if err != nil {
+		log.Panic("can not chat with model: ", err)
+	}
+	return strings.Trim(reply, "\". ")
+}
+
+func runLLMPlay(cmd *command, args []string) {
+	if len(args) != 1 {
+		cmd.fail("database expected")
+	}
+	d, err := loadDatabase(args[0])
+	if err != nil {
+		log.Panic("can not load db: ", err)
+	}
+
+	var picked []string
+	found, taught, totalQuestions := 0, 0, 0
+	for i := 0; i < *llmGames; i++ {
+		animal := pickAnimal(d.category(), picked)
+		picked = append(picked, animal)
+		g := newGame(d, newLLMPlayer(d.category(), animal))
+		g.play()
+		totalQuestions += g.questions
+		switch {
+		case g.found && sameName(g.answer.Animal, animal):
+			found++
+			fmt.Printf("%s: found with %d question(s)\n", animal, g.questions)
+		case g.found:
+			fmt.Printf("%s: model accepted %s\n", animal, g.answer.Animal)
+		default:
+			taught++
+			fmt.Printf("%s: not found, taught %s\n", animal, g.answer.Animal)
+		}
+	}
+
+	fmt.Printf("found: %d/%d, taught: %d, average questions: %.2f\n",
+		found, *llmGames, taught, float64(totalQuestions)/float64(max(*llmGames, 1)))
+	if *llmLearn {
+		err = d.save(args[0])
+		if err != nil {
+			log.Panic("can not save db: ", err)
+		}
+	}
+}
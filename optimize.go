@@ -0,0 +1,259 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Tree restructuring.  Trees grown one animal at a time ask many questions
+// before reaching animals taught early.  The optimizer rebuilds the tree top
+// down, choosing at each node the question that best balances the estimated
+// popularity of the remaining animals.
+//
+// A question can only be asked about a set of animals if the answer is known
+// for all of them.  Answers are known along the path leading to each animal,
+// for questions asked with the same wording in several branches and from an
+// optional attribute file.  The lowest common ancestor of any set of animals
+// always qualifies, so the original structure is a fallback.
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+)
+
+func init() {
+	cmd := &command{
+		Name:  "optimize",
+		Args:  "database-file",
+		Short: "restructure tree to minimize expected number of questions",
+		Run:   runOptimize,
+	}
+	optimizeAttributes = cmd.Flag.String("attributes", "", "CSV file of additional answers")
+	optimizeDryRun = cmd.Flag.Bool("n", false, "report gain without modifying database")
+	commands = append(commands, cmd)
+}
+
+var (
+	optimizeAttributes *string
+	optimizeDryRun     *bool
+)
+
+// Animal and what is known about it
+type animalFacts struct {
+	leaf     *node
+	weight   float64 // estimated probability of being chosen by player
+	answers  map[string]bool
+	conflict map[string]bool // questions answered both ways
+}
+
+func (c *animalFacts) learn(question string, yes bool) {
+	if c.conflict[question] {
+		return
+	}
+	if prev, ok := c.answers[question]; ok && prev != yes {
+		delete(c.answers, question)
+		c.conflict[question] = true
+		return
+	}
+	c.answers[question] = yes
+}
+
+type optimizer struct {
+	animals   []*animalFacts
+	questions []string // in order of first appearance
+}
+
+func newOptimizer(root *node, attrs *attributeTable) *optimizer {
+	o := new(optimizer)
+	byName := make(map[string]*animalFacts)
+	seen := make(map[string]bool)
+	addQuestion := func(q string) {
+		if !seen[q] {
+			seen[q] = true
+			o.questions = append(o.questions, q)
+		}
+	}
+
+	var walk func(n *node, path []step, weight float64)
+	walk = func(n *node, path []step, weight float64) {
+		if !n.isLeaf() {
+			addQuestion(n.Question)
+			p := n.yesProbability()
+			walk(n.No, append(path, step{n.Question, false}), weight*(1-p))
+			walk(n.Yes, append(path, step{n.Question, true}), weight*p)
+			return
+		}
+		c := byName[n.Animal]
+		if c == nil {
+			c = &animalFacts{leaf: n, answers: make(map[string]bool), conflict: make(map[string]bool)}
+			byName[n.Animal] = c
+			o.animals = append(o.animals, c)
+		}
+		c.weight += weight
+		for _, s := range path {
+			c.learn(s.question, s.yes)
+		}
+	}
+	walk(root, nil, 1)
+
+	if attrs != nil {
+		for _, q := range attrs.questions {
+			addQuestion(q)
+		}
+		for _, c := range o.animals {
+			for q, yes := range attrs.answers[c.leaf.Animal] {
+				// The tree has the last word.
+				if _, ok := c.answers[q]; !ok && !c.conflict[q] {
+					c.answers[q] = yes
+				}
+			}
+		}
+	}
+	return o
+}
+
+// Build tree discriminating cs
+func (o *optimizer) build(cs []*animalFacts) (*node, error) {
+	if len(cs) == 1 {
+		leaf := *cs[0].leaf
+		leaf.ID = ""
+		return &leaf, nil
+	}
+
+	var total float64
+	for _, c := range cs {
+		total += c.weight
+	}
+	best, bestScore := "", -1.0
+	for _, q := range o.questions {
+		var yesWeight float64
+		yeses, usable := 0, true
+		for _, c := range cs {
+			yes, ok := c.answers[q]
+			if !ok {
+				usable = false
+				break
+			}
+			if yes {
+				yeses++
+				yesWeight += c.weight
+			}
+		}
+		if !usable || yeses == 0 || yeses == len(cs) {
+			continue
+		}
+		if score := entropy(yesWeight / total); score > bestScore {
+			best, bestScore = q, score
+		}
+	}
+	if best == "" {
+		return nil, fmt.Errorf("no consistent question distinguishes %s and %s", cs[0].leaf.Animal, cs[1].leaf.Animal)
+	}
+
+	var no, yes []*animalFacts
+	for _, c := range cs {
+		if c.answers[best] {
+			yes = append(yes, c)
+		} else {
+			no = append(no, c)
+		}
+	}
+	n := &node{Question: best}
+	var err error
+	if n.No, err = o.build(no); err != nil {
+		return nil, err
+	}
+	if n.Yes, err = o.build(yes); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// Binary entropy
+func entropy(p float64) float64 {
+	if p <= 0 || p >= 1 {
+		return 0
+	}
+	return -p*math.Log2(p) - (1-p)*math.Log2(1-p)
+}
+
+// Expected number of questions asked in tree given animal weights
+func (o *optimizer) expectedDepth(root *node) float64 {
+	weights := make(map[string]float64)
+	var total float64
+	for _, c := range o.animals {
+		weights[c.leaf.Animal] = c.weight
+		total += c.weight
+	}
+	var sum float64
+	var walk func(n *node, depth int)
+	walk = func(n *node, depth int) {
+		if n.isLeaf() {
+			sum += weights[n.Animal] * float64(depth)
+			// Duplicates share the weight of the first one.
+			weights[n.Animal] = 0
+			return
+		}
+		walk(n.No, depth+1)
+		walk(n.Yes, depth+1)
+	}
+	walk(root, 0)
+	return sum / total
+}
+
+func runOptimize(cmd *command, args []string) {
+	if len(args) != 1 {
+		cmd.fail("database expected")
+	}
+	d, err := loadDatabase(args[0])
+	if err != nil {
+		log.Panic("can not load db: ", err)
+	}
+	var attrs *attributeTable
+	if *optimizeAttributes != "" {
+		attrs, err = loadAttributes(*optimizeAttributes)
+		if err != nil {
+			log.Panic("can not load attributes: ", err)
+		}
+	}
+
+	o := newOptimizer(d.Root, attrs)
+	root, err := o.build(o.animals)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "can not optimize: %s\n", err)
+		os.Exit(1)
+	}
+	before, after := o.expectedDepth(d.Root), o.expectedDepth(root)
+	fmt.Printf("expected questions per game: %.2f -> %.2f\n", before, after)
+	if *optimizeDryRun || after >= before {
+		return
+	}
+	if !confirmRebase(d) {
+		return
+	}
+	d.Root = root
+	d.rebase()
+	err = d.save(args[0])
+	if err != nil {
+		log.Panic("can not save db: ", err)
+	}
+}
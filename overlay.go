@@ -0,0 +1,173 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Personal overlays: animals a player teaches go to a file of their own
+// instead of the shared database, so that only they meet them until they
+// are submitted upstream.  The overlay holds the player's ops, replayed on
+// top of the shared tree when playing.
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+)
+
+var overlayFlag = flag.String("overlay", "", "keep animals taught in this personal file instead of the database")
+
+// Replica producing the ops of the overlay of player of replica.  Shared
+// ops then keep their own sequence numbers, which personal ones would
+// otherwise reuse once saved apart.
+func overlayReplica(replica string) string {
+	return replica + "/overlay"
+}
+
+type overlay struct {
+	Ops []*op `json:",omitempty"`
+}
+
+// Read overlay from file, a missing file being empty
+func loadOverlay(path string) (*overlay, error) {
+	ov := new(overlay)
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ov, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, ov); err != nil {
+		return nil, err
+	}
+	return ov, nil
+}
+
+func (ov *overlay) save(path string) error {
+	content, err := json.MarshalIndent(ov, "", "    ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, content, 0600)
+}
+
+// Add ops of overlay at path to d.  Ops the shared database already has,
+// e.g. because they were submitted, are not personal anymore.
+func (d *database) applyOverlay(path string) {
+	ov, err := loadOverlay(path)
+	if err != nil {
+		log.Panic("can not load overlay: ", err)
+	}
+	known := d.knownOps()
+	d.personal = make(map[string]bool)
+	for _, o := range ov.Ops {
+		if !known[o.id()] {
+			d.personal[o.id()] = true
+		}
+	}
+	d.merge(ov.Ops)
+}
+
+// Write personal ops of d to overlay file and the rest to database file
+func (d *database) saveWithOverlay(path, overlayPath string) error {
+	ov := new(overlay)
+	shared := *d
+	shared.Ops = nil
+	for _, o := range d.Ops {
+		if d.personal[o.id()] {
+			ov.Ops = append(ov.Ops, o)
+		} else {
+			shared.Ops = append(shared.Ops, o)
+		}
+	}
+	if err := ov.save(overlayPath); err != nil {
+		return err
+	}
+	shared.replay()
+	return shared.save(path)
+}
+
+func init() {
+	cmd := &command{
+		Name:  "submit",
+		Args:  "database-file overlay-file",
+		Short: "add animals of personal overlay to shared database",
+		Run:   runSubmit,
+	}
+	submitDryRun = cmd.Flag.Bool("n", false, "list proposed changes without modifying database")
+	commands = append(commands, cmd)
+}
+
+var submitDryRun *bool
+
+// Description of op listed by submit -n
+func (o *op) String() string {
+	switch o.Kind {
+	case opLearn:
+		answer := "no"
+		if o.IsYes {
+			answer = "yes"
+		}
+		return fmt.Sprintf("learn %s, answering %s to %q", o.Animal, answer, o.Question)
+	case opRename:
+		return "rename to " + o.Animal
+	case opEdit:
+		return "rephrase as " + o.Question
+	default:
+		return o.Kind + " " + o.Animal
+	}
+}
+
+func runSubmit(cmd *command, args []string) {
+	if len(args) != 2 {
+		cmd.fail("database and overlay expected")
+	}
+	d, err := loadDatabase(args[0])
+	if err != nil {
+		log.Panic("can not load db: ", err)
+	}
+	ov, err := loadOverlay(args[1])
+	if err != nil {
+		log.Panic("can not load overlay: ", err)
+	}
+	if *submitDryRun {
+		known := d.knownOps()
+		for _, o := range ov.Ops {
+			if !known[o.id()] {
+				fmt.Println(o)
+			}
+		}
+		return
+	}
+	n := d.merge(ov.Ops)
+	fmt.Printf("%s: %d new change(s)\n", args[1], n)
+	if n == 0 {
+		return
+	}
+	err = d.save(args[0])
+	if err != nil {
+		log.Panic("can not save db: ", err)
+	}
+}
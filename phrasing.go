@@ -0,0 +1,143 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Alternative phrasings of questions.  Each game asks the variant asked the
+// fewest times so far and records whether the program found the animal,
+// showing curators which wording works best.  Variants are in the database
+// language: players of other languages get the translation.
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+func init() {
+	cmd := &command{
+		Name:  "phrasings",
+		Args:  "database-file [phrasing]",
+		Short: "list, add or adopt phrasings of question",
+		Run:   runPhrasings,
+	}
+	phrasingsPath = cmd.Flag.String("path", "", "answers leading to question from root, e.g. yny")
+	phrasingsUse = cmd.Flag.Int("use", 0, "make phrasing of this number the question and stop trying others")
+	commands = append(commands, cmd)
+}
+
+var (
+	phrasingsPath *string
+	phrasingsUse  *int
+)
+
+type variant struct {
+	Text string
+
+	// Number of games that asked the variant and that the program won
+	Asked, Found int `json:",omitempty"`
+}
+
+// Proportion of games asking v that the program won
+func (v variant) successRate() float64 {
+	if v.Asked == 0 {
+		return 0
+	}
+	return float64(v.Found) / float64(v.Asked)
+}
+
+// Text of question n to ask in this game
+func (g *game) phrase(n *node) string {
+	if _, ok := n.Translations[language()]; ok || len(n.Variants) == 0 {
+		return n.localized()
+	}
+	best := 0
+	for i, v := range n.Variants {
+		if v.Asked < n.Variants[best].Asked {
+			best = i
+		}
+	}
+	g.phrased[n] = best
+	return n.Variants[best].Text
+}
+
+// Record outcome of game for variants asked
+func (g *game) scorePhrasings() {
+	for n, i := range g.phrased {
+		n.Variants[i].Asked++
+		if g.found {
+			n.Variants[i].Found++
+		}
+	}
+}
+
+func runPhrasings(cmd *command, args []string) {
+	if len(args) < 1 {
+		cmd.fail("database expected")
+	}
+	d, err := loadDatabase(args[0])
+	if err != nil {
+		log.Panic("can not load db: ", err)
+	}
+	n, err := d.followPath(*phrasingsPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", args[0], err)
+		os.Exit(1)
+	}
+
+	switch {
+	case *phrasingsUse != 0:
+		if *phrasingsUse < 1 || *phrasingsUse > len(n.Variants) {
+			fmt.Fprintf(os.Stderr, "%s: no phrasing %d\n", args[0], *phrasingsUse)
+			os.Exit(1)
+		}
+		d.editQuestion(n, n.Variants[*phrasingsUse-1].Text)
+	case len(args) > 1:
+		text := strings.TrimSpace(strings.Join(args[1:], " "))
+		if len(n.Variants) == 0 {
+			n.Variants = []variant{{Text: n.Question}}
+		}
+		for _, v := range n.Variants {
+			if sameName(v.Text, text) {
+				fmt.Fprintf(os.Stderr, "%s: phrasing %q already tried\n", args[0], v.Text)
+				os.Exit(1)
+			}
+		}
+		if v, reason := moderate(text, true); v == reject {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", text, reason)
+			os.Exit(1)
+		}
+		n.Variants = append(n.Variants, variant{Text: text})
+	default:
+		fmt.Println(n.Question)
+		for i, v := range n.Variants {
+			fmt.Printf("%d) %s: won %d of %d games (%.0f%%)\n", i+1, v.Text, v.Found, v.Asked, 100*v.successRate())
+		}
+		return
+	}
+
+	err = d.save(args[0])
+	if err != nil {
+		log.Panic("can not save db: ", err)
+	}
+}
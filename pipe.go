@@ -0,0 +1,100 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Behavior when the program is driven by pipes or expect-style scripts
+// rather than a person at a terminal.  Prompts then end with a newline so
+// that drivers can wait for whole lines, answers are echoed after "> " so
+// that transcripts read like sessions, and an unexpected answer ends the
+// program with an error instead of asking again.
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sync"
+)
+
+var terminalFlag = flag.String("terminal", "auto", "whether stdin and stdout are an interactive terminal: yes, no or auto")
+
+var (
+	pipedOnce sync.Once
+	isPiped   bool
+)
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Whether input or output is not an interactive terminal
+func piped() bool {
+	pipedOnce.Do(func() {
+		switch *terminalFlag {
+		case "yes":
+		case "no":
+			isPiped = true
+		case "auto":
+			isPiped = !isTerminal(os.Stdin) || !isTerminal(os.Stdout)
+		default:
+			fmt.Fprintf(os.Stderr, "-terminal: yes, no or auto expected\n")
+			os.Exit(1)
+		}
+	})
+	return isPiped
+}
+
+// Print prompt before reading answer
+func showPrompt(prompt string) {
+	if piped() {
+		fmt.Fprintln(out, prompt)
+	} else {
+		fmt.Fprint(out, prompt+" ")
+	}
+}
+
+// Show answer just read when the terminal did not
+func echoAnswer(answer string) {
+	if piped() {
+		fmt.Fprintln(out, "> "+answer)
+	}
+}
+
+// Tell player why answer is not acceptable, or give up if nobody is there
+// to answer again
+func rejectAnswer(why string) {
+	if !piped() {
+		if why != "" {
+			fmt.Fprintln(out, output().message(why))
+		}
+		return
+	}
+	if why == "" {
+		why = "empty answer"
+	}
+	fmt.Fprintln(os.Stderr, why)
+	if atEndOfInput != nil {
+		atEndOfInput()
+	}
+	os.Exit(1)
+}
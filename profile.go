@@ -0,0 +1,207 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+)
+
+// Statistics of a player, kept in each database played with
+type profile struct {
+	Games     int
+	Stumps    int // games the program failed
+	Taught    int // animals contributed
+	Questions int // questions answered
+
+	Streak       int      // games currently failed in a row by the program
+	LongestGame  int      // most questions answered in a game
+	Achievements []string `json:",omitempty"` // IDs of unlocked achievements
+}
+
+// Profile of named player, created if needed
+func (d *database) profile(name string) *profile {
+	if d.Profiles == nil {
+		d.Profiles = make(map[string]*profile)
+	}
+	p := d.Profiles[name]
+	if p == nil {
+		p = new(profile)
+		d.Profiles[name] = p
+	}
+	return p
+}
+
+// Update statistics with outcome of g and return achievements unlocked
+func (p *profile) record(g *game) []*achievement {
+	p.Games++
+	p.Questions += g.questions
+	if !g.found && !g.forfeited {
+		p.Stumps++
+		p.Streak++
+	} else {
+		p.Streak = 0
+	}
+	if g.taught && g.teacher == "" {
+		p.Taught++
+	}
+	if g.questions > p.LongestGame {
+		p.LongestGame = g.questions
+	}
+	return p.unlock()
+}
+
+// Update statistics of player who taught animal of g in hotseat match and
+// return achievements unlocked
+func (p *profile) recordTeaching(g *game) []*achievement {
+	if g.taught {
+		p.Taught++
+	}
+	return p.unlock()
+}
+
+func (p *profile) stumpRate() float64 {
+	if p.Games == 0 {
+		return 0
+	}
+	return float64(p.Stumps) / float64(p.Games)
+}
+
+func (p *profile) add(q *profile) {
+	p.Games += q.Games
+	p.Stumps += q.Stumps
+	p.Taught += q.Taught
+	p.Questions += q.Questions
+	if q.LongestGame > p.LongestGame {
+		p.LongestGame = q.LongestGame
+	}
+	for _, id := range q.Achievements {
+		if !p.has(id) {
+			p.Achievements = append(p.Achievements, id)
+		}
+	}
+}
+
+type achievement struct {
+	id      string
+	title   string
+	reached func(p *profile) bool
+}
+
+var achievements = []*achievement{
+	{"first-game", "Played a first game", func(p *profile) bool { return p.Games >= 1 }},
+	{"games-100", "Played 100 games", func(p *profile) bool { return p.Games >= 100 }},
+	{"taught-1", "Taught a first animal", func(p *profile) bool { return p.Taught >= 1 }},
+	{"taught-10", "Taught 10 animals", func(p *profile) bool { return p.Taught >= 10 }},
+	{"taught-50", "Taught 50 animals", func(p *profile) bool { return p.Taught >= 50 }},
+	{"streak-5", "Stumped the computer 5 times in a row", func(p *profile) bool { return p.Streak >= 5 }},
+	{"long-game-30", "Reached a 30-question game", func(p *profile) bool { return p.LongestGame >= 30 }},
+}
+
+func (p *profile) has(id string) bool {
+	for _, a := range p.Achievements {
+		if a == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Record and return achievements newly reached
+func (p *profile) unlock() []*achievement {
+	var unlocked []*achievement
+	for _, a := range achievements {
+		if !p.has(a.id) && a.reached(p) {
+			p.Achievements = append(p.Achievements, a.id)
+			unlocked = append(unlocked, a)
+		}
+	}
+	return unlocked
+}
+
+func init() {
+	cmd := &command{
+		Name:  "leaderboard",
+		Args:  "database-file...",
+		Short: "rank players by their statistics",
+		Run:   runLeaderboard,
+	}
+	leaderboardBy = cmd.Flag.String("by", "taught", "ranking criterion: taught, stumps, rate or games")
+	commands = append(commands, cmd)
+}
+
+var leaderboardBy *string
+
+func runLeaderboard(cmd *command, args []string) {
+	if len(args) == 0 {
+		cmd.fail("database expected")
+	}
+	keys := map[string]func(p *profile) float64{
+		"taught": func(p *profile) float64 { return float64(p.Taught) },
+		"stumps": func(p *profile) float64 { return float64(p.Stumps) },
+		"rate":   func(p *profile) float64 { return p.stumpRate() },
+		"games":  func(p *profile) float64 { return float64(p.Games) },
+	}
+	key, ok := keys[*leaderboardBy]
+	if !ok {
+		cmd.fail("unknown criterion %q", *leaderboardBy)
+	}
+
+	totals := make(map[string]*profile)
+	for _, path := range args {
+		d, err := loadDatabase(path)
+		if err != nil {
+			log.Panic("can not load db: ", err)
+		}
+		for name, p := range d.Profiles {
+			if totals[name] == nil {
+				totals[name] = new(profile)
+			}
+			totals[name].add(p)
+		}
+	}
+	if len(totals) == 0 {
+		fmt.Fprintln(os.Stderr, "no player yet: play with -player name")
+		os.Exit(1)
+	}
+
+	var names []string
+	for name := range totals {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := key(totals[names[i]]), key(totals[names[j]])
+		if a != b {
+			return a > b
+		}
+		return names[i] < names[j]
+	})
+	fmt.Printf("%4s  %-16s %6s %6s %6s %6s %6s\n", "rank", "player", "games", "stumps", "rate", "taught", "awards")
+	for i, name := range names {
+		p := totals[name]
+		fmt.Printf("%4d  %-16s %6d %6d %5.0f%% %6d %6d\n",
+			i+1, name, p.Games, p.Stumps, 100*p.stumpRate(), p.Taught, len(p.Achievements))
+	}
+}
@@ -0,0 +1,64 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Abandoning games: answering "quit" to any prompt of a game ends it
+// without revealing anything, "giveup" also offers to teach the animal.
+
+import "errors"
+
+var (
+	errQuit   = errors.New("quit")
+	errGiveUp = errors.New("give up")
+)
+
+// Whether a game is in progress
+var playing bool
+
+// Abandon game if player asked for it
+func checkQuit(answer string) {
+	if !playing {
+		return
+	}
+	switch {
+	case sameName(answer, tr("quit")) || sameName(answer, "quit"):
+		panic(errQuit)
+	case sameName(answer, tr("giveup")) || sameName(answer, "giveup") || sameName(answer, "give up"):
+		panic(errGiveUp)
+	}
+}
+
+// Let player who gave up teach their animal where the game stopped
+func (g *game) offerToLearn() {
+	defer func() {
+		// Giving up again ends the game for good.
+		if err := recover(); err != nil && err != errQuit && err != errGiveUp {
+			panic(err)
+		}
+	}()
+	playing = true
+	defer func() { playing = false }()
+	if g.ui.askYesNo(tr("Do you want to tell me what it was?")) {
+		g.giveUp(g.current())
+	}
+}
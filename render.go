@@ -0,0 +1,166 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Presentation of terminal output, kept apart from the reading of answers
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Where prompts and messages go, nowhere in quiet mode
+var out io.Writer = os.Stdout
+
+var (
+	flairFlag      = flag.Bool("flair", false, "playful output with mascot, emoji and banners")
+	accessibleFlag = flag.Bool("accessible", false, "screen-reader friendly output without decorations")
+)
+
+type promptKind int
+
+const (
+	textPrompt     promptKind = iota // free-form answer
+	yesNoPrompt                      // yes or no answer
+	questionPrompt                   // question of tree
+	guessPrompt                      // guess of animal
+)
+
+// Way of decorating terminal output
+type renderer interface {
+	prompt(kind promptKind, text string) string
+	message(text string) string
+
+	// Shown when game starts and ends, empty if nothing
+	start() string
+	end(g *game) string
+}
+
+func output() renderer {
+	if *accessibleFlag {
+		return accessible{}
+	}
+	if *flairFlag {
+		return flair{}
+	}
+	return plain{}
+}
+
+type plain struct{}
+
+func (plain) prompt(_ promptKind, text string) string { return text }
+func (plain) message(text string) string              { return text }
+func (plain) start() string                           { return "" }
+func (plain) end(*game) string                        { return "" }
+
+// Print banner unless empty
+func showBanner(s string) {
+	if s != "" {
+		fmt.Fprintln(out, s)
+	}
+}
+
+type flair struct{}
+
+const mascot = `
+   ,_,
+  (O,O)
+  (   )
+ --"-"--`
+
+func (flair) prompt(kind promptKind, text string) string {
+	switch kind {
+	case questionPrompt:
+		return "❓ " + text
+	case guessPrompt:
+		return "🎯 " + text
+	}
+	return "👉 " + text
+}
+
+func (flair) message(text string) string {
+	if text == "" {
+		return text
+	}
+	return "💬 " + text
+}
+
+func (flair) start() string {
+	return mascot + "  " + tr("Think of something and I will guess it!")
+}
+
+func (flair) end(g *game) string {
+	switch {
+	case g.forfeited:
+		return ""
+	case g.found:
+		return box("🎉 " + tr("I win!") + " 🎉")
+	}
+	return box("🏆 " + tr("You win!") + " 🏆")
+}
+
+// Text framed by ASCII art
+func box(text string) string {
+	width := 0
+	for _, r := range text {
+		width++
+		// Emoji are usually twice as wide as letters.
+		if unicode.Is(unicode.So, r) {
+			width++
+		}
+	}
+	line := "+" + strings.Repeat("-", width+4) + "+"
+	return line + "\n|  " + text + "  |\n" + line
+}
+
+// Undecorated output with one sentence per line and the expected kind of
+// answer announced after each prompt
+type accessible struct{}
+
+func (accessible) prompt(kind promptKind, text string) string {
+	text = undecorated(text)
+	if kind == textPrompt {
+		return text + "\n" + tr("Type your answer:")
+	}
+	return text + "\n" + tr("Answer yes or no:")
+}
+
+func (accessible) message(text string) string { return undecorated(text) }
+func (accessible) start() string              { return "" }
+func (accessible) end(*game) string           { return "" }
+
+// Text without emoji and arrows, which screen readers spell out or skip
+func undecorated(text string) string {
+	text = strings.ReplaceAll(text, " → ", ", ")
+	text = strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.So, r) {
+			return -1
+		}
+		return r
+	}, text)
+	return strings.Join(strings.Fields(text), " ")
+}
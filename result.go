@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Outcome of games for scripts
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+var (
+	quietFlag  = flag.Bool("quiet", false, "play a single game printing only its result")
+	outputFlag = flag.String("output", "text", "format of game results: text or json")
+)
+
+type answeredQuestion struct {
+	Question string
+	Yes      bool
+}
+
+// Result of game as printed by -output=json
+type result struct {
+	Animal    string // empty if forfeited
+	Found     bool   // whether program guessed animal
+	Taught    bool   // whether animal was learned
+	Forfeited bool
+	Paused    bool
+	Abandoned bool
+	Questions int
+	Path      []answeredQuestion
+}
+
+func gameResult(g *game) *result {
+	r := &result{Found: g.found, Taught: g.taught, Forfeited: g.forfeited, Paused: g.paused, Abandoned: g.abandoned, Questions: g.questions}
+	if g.answer != nil {
+		r.Animal = g.answer.Animal
+	}
+	r.Path = []answeredQuestion{}
+	for _, s := range g.path {
+		r.Path = append(r.Path, answeredQuestion{s.question, s.yes})
+	}
+	return r
+}
+
+func checkOutputFlag() {
+	if *outputFlag != "text" && *outputFlag != "json" {
+		fmt.Fprintf(os.Stderr, "unknown output format %q\n", *outputFlag)
+		os.Exit(1)
+	}
+}
+
+// Print result of game if requested
+func printResult(g *game) {
+	switch *outputFlag {
+	case "json":
+		content, err := json.Marshal(gameResult(g))
+		if err != nil {
+			log.Panic("can not encode result: ", err)
+		}
+		fmt.Println(string(content))
+	case "text":
+		if !*quietFlag {
+			return
+		}
+		r := gameResult(g)
+		switch {
+		case r.Paused:
+			fmt.Println("paused")
+		case r.Abandoned:
+			fmt.Println("abandoned")
+		case r.Forfeited:
+			fmt.Println("forfeited")
+		case r.Found:
+			fmt.Printf("found %s with %d question(s)\n", r.Animal, r.Questions)
+		default:
+			fmt.Printf("taught %s after %d question(s)\n", r.Animal, r.Questions)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Pausing games, e.g. for bot frontends whose conversations stop for hours.
+// Answering "pause" to a question saves the answers given so far in the
+// database and -resume continues from there.
+
+import (
+	"errors"
+	"flag"
+)
+
+var resumeFlag = flag.Bool("resume", false, "continue game paused by answering \"pause\" to a question")
+
+var errPause = errors.New("pause")
+
+// Whether the game can be paused, false when several players take turns
+var pausable bool
+
+// Game paused by player
+type pausedGame struct {
+	// Answers leading from root, a string of y and n
+	Answers string
+
+	// IDs of animals already guessed wrongly
+	Rejected []string `json:",omitempty"`
+}
+
+// Pause game if player asked for it
+func checkPause(answer string) {
+	if pausable && (sameName(answer, tr("pause")) || sameName(answer, "pause")) {
+		panic(errPause)
+	}
+}
+
+// Node reached by questions answered so far
+func (g *game) current() *node {
+	if g.at == nil {
+		return g.db.Root
+	}
+	return g.at
+}
+
+func (d *database) pause(player string, g *game) {
+	p := &pausedGame{Answers: pathString(pathTo(d.Root, g.current()))}
+	for leaf := range g.rejected {
+		p.Rejected = append(p.Rejected, leaf.ID)
+	}
+	if d.Paused == nil {
+		d.Paused = make(map[string]*pausedGame)
+	}
+	d.Paused[player] = p
+}
+
+// Restore game paused by player if any, reminding them of their answers.
+// Answers are replayed from the root and stop at a leaf if the tree changed
+// since.
+func (g *game) resume(player string) {
+	p := g.db.Paused[player]
+	if p == nil {
+		return
+	}
+	delete(g.db.Paused, player)
+	index := make(map[string]*node)
+	indexTree(g.db.Root, index)
+	for _, id := range p.Rejected {
+		if leaf := index[id]; leaf != nil && leaf.isLeaf() {
+			g.rejected[leaf] = true
+		}
+	}
+	n := g.db.Root
+	for _, a := range p.Answers {
+		if n.isLeaf() {
+			break
+		}
+		yes := a == 'y'
+		g.path = append(g.path, step{n.localized(), yes})
+		g.asked = append(g.asked, n)
+		g.questions++
+		if yes {
+			n = n.Yes
+		} else {
+			n = n.No
+		}
+	}
+	g.at = n
+	if len(g.path) > 0 {
+		g.ui.tell(g.trail())
+	}
+}
@@ -0,0 +1,162 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Reverse mode: the program chooses an animal and answers the player's
+// questions from what the tree and an optional attribute file know about it.
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+)
+
+func init() {
+	cmd := &command{
+		Name:  "reverse",
+		Args:  "database-file",
+		Short: "let the player guess an animal chosen by the program",
+		Run:   runReverse,
+	}
+	reverseAttributes = cmd.Flag.String("attributes", "", "CSV file of additional answers")
+	commands = append(commands, cmd)
+}
+
+var reverseAttributes *string
+
+// Minimum word overlap for a player question to match a known one
+const questionMatchThreshold = 0.6
+
+func runReverse(cmd *command, args []string) {
+	if len(args) != 1 {
+		cmd.fail("database expected")
+	}
+	d, err := loadDatabase(args[0])
+	if err != nil {
+		log.Panic("can not load db: ", err)
+	}
+	var attrs *attributeTable
+	if *reverseAttributes != "" {
+		attrs, err = loadAttributes(*reverseAttributes)
+		if err != nil {
+			log.Panic("can not load attributes: ", err)
+		}
+	}
+	stdin = bufio.NewReader(os.Stdin)
+	facts := newOptimizer(d.Root, attrs).animals
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	again := true
+	for again {
+		playReverse(d, facts[rng.Intn(len(facts))], facts)
+		again = askYesNo("%s", tr("Play another game?"))
+	}
+}
+
+func playReverse(d *database, secret *animalFacts, facts []*animalFacts) {
+	fmt.Printf(tr("I have picked one %s I know.  Ask yes-or-no questions or make a guess.")+"\n", tr(d.category()))
+	for questions := 1; ; questions++ {
+		s := ask("%s", tr("Your question, guess or \"give up\":"))
+		if s == "give up" || s == tr("give up") {
+			fmt.Printf(tr("It was %s.")+"\n", d.named(secret.leaf.Animal))
+			return
+		}
+		if isAnimal(s, facts) {
+			if sameWords(s, secret.leaf.Animal) {
+				fmt.Printf(tr("Yes!  You found it with %d question(s).")+"\n", questions)
+				return
+			}
+			fmt.Println(tr("No."))
+			continue
+		}
+		q, ok := matchQuestion(s, secret.answers)
+		switch {
+		case !ok:
+			fmt.Println(tr("I don't know."))
+		case secret.answers[q]:
+			fmt.Printf(tr("Yes. (%s)")+"\n", q)
+		default:
+			fmt.Printf(tr("No. (%s)")+"\n", q)
+		}
+	}
+}
+
+func isAnimal(s string, facts []*animalFacts) bool {
+	for _, f := range facts {
+		if sameWords(s, f.leaf.Animal) {
+			return true
+		}
+	}
+	return false
+}
+
+// Known question closest to s if close enough
+func matchQuestion(s string, answers map[string]bool) (best string, ok bool) {
+	words := wordSet(s)
+	bestScore := 0.0
+	for q := range answers {
+		score := overlap(words, wordSet(q))
+		if score > bestScore || score == bestScore && q < best {
+			best, bestScore = q, score
+		}
+	}
+	return best, bestScore >= questionMatchThreshold
+}
+
+func sameWords(a, b string) bool {
+	return strings.Join(words(a), " ") == strings.Join(words(b), " ")
+}
+
+// Lower-case words of s, ignoring punctuation
+func words(s string) []string {
+	return strings.FieldsFunc(fold(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func wordSet(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range words(s) {
+		set[w] = true
+	}
+	return set
+}
+
+// Jaccard index of word sets
+func overlap(a, b map[string]bool) float64 {
+	common := 0
+	for w := range a {
+		if b[w] {
+			common++
+		}
+	}
+	union := len(a) + len(b) - common
+	if union == 0 {
+		return 0
+	}
+	return float64(common) / float64(union)
+}
@@ -0,0 +1,450 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Multiplayer rooms hosted by "ask-and-learn serve".  A player creates a room
+// and shares its code.  Everybody who joined votes on each answer and the
+// majority wins.  Every room runs its games in a goroutine that holds the
+// server lock except while waiting for votes.
+//
+//	POST /rooms?name=N               create room, N joins it
+//	POST /rooms/CODE/join?name=N     join room
+//	GET  /rooms/CODE                 state of room (JSON)
+//	POST /rooms/CODE/vote?name=N&answer=A
+//	POST /rooms/CODE/leave?name=N    leave room, closed once empty
+//	GET  /                           browser client
+
+import (
+	"crypto/rand"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	roomCodeLetters = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+	roomCodeLength  = 4
+
+	// Delay after first vote before counting votes of players who did not
+	// vote yet as abstentions
+	roomVoteTimeout = time.Minute
+
+	// Rooms without vote during this delay are closed
+	roomIdleTimeout = 30 * time.Minute
+
+	// Number of messages kept in room state
+	roomMessages = 20
+
+	// Number of rooms open at once
+	maxRooms = 100
+)
+
+// Raised in room goroutine to abandon game
+var errRoomClosed = errors.New("room closed")
+
+type room struct {
+	sync.Mutex
+	code     string
+	players  []string
+	prompt   string // question being voted on, if any
+	yesNo    bool   // whether prompt expects yes or no
+	votes    map[string]string
+	messages []string
+	image    string // picture of animal being guessed
+	over     bool
+
+	srv       *server
+	decided   chan string   // majority answer to prompt
+	voted     chan struct{} // first vote on prompt or departure of last player
+	lastVote  time.Time
+	firstVote time.Time // of prompt
+}
+
+// State of room sent to players
+type roomState struct {
+	Code     string
+	Players  []string
+	Prompt   string
+	YesNo    bool
+	Votes    map[string]string
+	Messages []string
+	Image    string `json:",omitempty"`
+	Over     bool
+}
+
+// Rooms of server by code
+var (
+	roomsLock sync.Mutex
+	rooms     = make(map[string]*room)
+)
+
+func newRoomCode() string {
+	buf := make([]byte, roomCodeLength)
+	if _, err := rand.Read(buf); err != nil {
+		log.Panic("can not generate room code: ", err)
+	}
+	for i, b := range buf {
+		buf[i] = roomCodeLetters[int(b)%len(roomCodeLetters)]
+	}
+	return string(buf)
+}
+
+// Open room, nil if too many are
+func (s *server) newRoom() *room {
+	roomsLock.Lock()
+	defer roomsLock.Unlock()
+	if len(rooms) >= maxRooms {
+		return nil
+	}
+	code := newRoomCode()
+	for rooms[code] != nil {
+		code = newRoomCode()
+	}
+	r := &room{code: code, srv: s, votes: make(map[string]string), decided: make(chan string, 1),
+		voted: make(chan struct{}, 1), lastVote: time.Now()}
+	rooms[code] = r
+	go r.run()
+	return r
+}
+
+func lookupRoom(code string) *room {
+	roomsLock.Lock()
+	defer roomsLock.Unlock()
+	return rooms[strings.ToUpper(code)]
+}
+
+// Play games until players stop or leave
+func (r *room) run() {
+	defer func() {
+		roomsLock.Lock()
+		delete(rooms, r.code)
+		roomsLock.Unlock()
+		r.Lock()
+		r.over = true
+		r.Unlock()
+		if err := recover(); err != nil && err != errRoomClosed {
+			panic(err)
+		}
+	}()
+
+	r.srv.Lock()
+	for {
+		g := newGame(r.srv.db, r)
+		g.play()
+		r.srv.save()
+		if !r.askYesNo(tr("Play another game?")) {
+			break
+		}
+	}
+	r.srv.Unlock()
+}
+
+// Wait for players to decide answer to prompt.  Must be called with server
+// lock held.
+func (r *room) vote(prompt string, yesNo bool) string {
+	r.Lock()
+	r.prompt = prompt
+	r.yesNo = yesNo
+	r.votes = make(map[string]string)
+	r.Unlock()
+
+	r.srv.Unlock()
+	var answer string
+	for answer == "" {
+		r.Lock()
+		closed := len(r.players) == 0
+		wait := roomIdleTimeout - time.Since(r.lastVote)
+		if len(r.votes) > 0 {
+			wait = roomVoteTimeout - time.Since(r.firstVote)
+			if wait <= 0 {
+				answer = r.majority()
+			}
+		}
+		r.Unlock()
+		if closed || wait <= 0 && answer == "" {
+			panic(errRoomClosed)
+		}
+		if answer != "" {
+			break
+		}
+		select {
+		case answer = <-r.decided:
+		case <-r.voted:
+		case <-time.After(wait):
+		}
+	}
+	r.tell(prompt + " " + answer)
+	r.Lock()
+	r.image = ""
+	r.Unlock()
+	r.srv.Lock()
+	return answer
+}
+
+// Most frequent vote.  Ties are broken alphabetically.  Must be called with
+// room lock held and clears prompt.
+func (r *room) majority() string {
+	counts := make(map[string]int)
+	for _, v := range r.votes {
+		counts[v]++
+	}
+	best := ""
+	for v, n := range counts {
+		if n > counts[best] || n == counts[best] && v < best {
+			best = v
+		}
+	}
+	r.prompt = ""
+	r.votes = make(map[string]string)
+	return best
+}
+
+func (r *room) ask(prompt string) string {
+	return r.vote(prompt, false)
+}
+
+func (r *room) askYesNo(prompt string) bool {
+	return r.vote(prompt, true) == "yes"
+}
+
+func (r *room) showImage(url string) {
+	r.Lock()
+	defer r.Unlock()
+	r.image = url
+}
+
+func (r *room) tell(msg string) {
+	r.Lock()
+	defer r.Unlock()
+	r.messages = append(r.messages, msg)
+	if len(r.messages) > roomMessages {
+		r.messages = r.messages[len(r.messages)-roomMessages:]
+	}
+}
+
+func (r *room) join(name string) {
+	r.Lock()
+	defer r.Unlock()
+	for _, p := range r.players {
+		if p == name {
+			return
+		}
+	}
+	r.players = append(r.players, name)
+	sort.Strings(r.players)
+}
+
+func (r *room) leave(name string) {
+	r.Lock()
+	defer r.Unlock()
+	for i, p := range r.players {
+		if p == name {
+			r.players = append(r.players[:i], r.players[i+1:]...)
+			delete(r.votes, name)
+			break
+		}
+	}
+	switch {
+	case len(r.players) == 0:
+		r.signal()
+	case r.prompt != "" && len(r.votes) == len(r.players):
+		r.decided <- r.majority()
+	}
+}
+
+// Wake up room goroutine waiting for votes.  Must be called with room lock
+// held.
+func (r *room) signal() {
+	select {
+	case r.voted <- struct{}{}:
+	default:
+	}
+}
+
+// Record vote.  Returns an error message if rejected.
+func (r *room) castVote(name, answer string) string {
+	r.Lock()
+	defer r.Unlock()
+	if r.prompt == "" {
+		return "no question pending"
+	}
+	joined := false
+	for _, p := range r.players {
+		joined = joined || p == name
+	}
+	if !joined {
+		return "join room first"
+	}
+	if r.yesNo {
+		yes, ok := parseYesNo(answer)
+		switch {
+		case !ok:
+			return "yes or no expected"
+		case yes:
+			answer = "yes"
+		default:
+			answer = "no"
+		}
+	}
+	r.votes[name] = answer
+	r.lastVote = time.Now()
+	if len(r.votes) == 1 {
+		r.firstVote = r.lastVote
+		r.signal()
+	}
+	if len(r.votes) == len(r.players) {
+		r.decided <- r.majority()
+	}
+	return ""
+}
+
+func (s *server) handleRooms() {
+	http.HandleFunc("/rooms", s.handleNewRoom)
+	http.HandleFunc("/rooms/", handleRoom)
+	http.HandleFunc("/", handleClient)
+}
+
+func (s *server) handleNewRoom(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		http.Error(w, "name expected", http.StatusBadRequest)
+		return
+	}
+	rm := s.newRoom()
+	if rm == nil {
+		http.Error(w, "too many rooms", http.StatusServiceUnavailable)
+		return
+	}
+	rm.join(name)
+	rm.writeState(w)
+}
+
+func handleRoom(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/rooms/"), "/")
+	rm := lookupRoom(parts[0])
+	if rm == nil {
+		http.Error(w, "no such room", http.StatusNotFound)
+		return
+	}
+	action := ""
+	if len(parts) > 1 {
+		action = parts[1]
+	}
+	name := strings.TrimSpace(r.FormValue("name"))
+	switch {
+	case action == "" && r.Method == "GET":
+	case action == "join" && r.Method == "POST" && name != "":
+		rm.join(name)
+	case action == "leave" && r.Method == "POST" && name != "":
+		rm.leave(name)
+	case action == "vote" && r.Method == "POST":
+		if msg := rm.castVote(name, strings.TrimSpace(r.FormValue("answer"))); msg != "" {
+			http.Error(w, msg, http.StatusConflict)
+			return
+		}
+	default:
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	rm.writeState(w)
+}
+
+func (r *room) writeState(w http.ResponseWriter) {
+	r.Lock()
+	st := &roomState{r.code, append([]string{}, r.players...), r.prompt, r.yesNo, make(map[string]string),
+		append([]string{}, r.messages...), r.image, r.over}
+	for name, v := range r.votes {
+		st.Votes[name] = v
+	}
+	r.Unlock()
+	writeJSON(w, st)
+}
+
+func handleClient(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(roomClient))
+}
+
+// Minimal browser client polling room state
+const roomClient = `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>ask-and-learn</title></head>
+<body>
+<div id="lobby">
+<p>Name: <input id="name"></p>
+<p><button onclick="create()">Create room</button>
+or code <input id="code" size="4"> <button onclick="join()">Join room</button></p>
+</div>
+<div id="game" hidden>
+<h2>Room <span id="room"></span></h2>
+<p>Players: <span id="players"></span></p>
+<ul id="messages"></ul>
+<p><img id="image" hidden style="max-width: 20em"></p>
+<p id="prompt"></p>
+<p id="yesno" hidden><button onclick="vote('yes')">Yes</button> <button onclick="vote('no')">No</button></p>
+<p id="text" hidden><input id="answer"> <button onclick="vote(document.getElementById('answer').value)">Answer</button></p>
+<p id="votes"></p>
+<p><button onclick="leave()">Leave room</button></p>
+</div>
+<script>
+var code = "", me = "";
+function $(id) { return document.getElementById(id); }
+function call(method, path, params) {
+	var q = new URLSearchParams(params).toString();
+	return fetch(path + "?" + q, {method: method}).then(function(r) {
+		if (!r.ok) { return r.text().then(function(t) { throw new Error(t); }); }
+		return r.json();
+	});
+}
+function enter(state) { code = state.Code; $("lobby").hidden = true; $("game").hidden = false; show(state); setInterval(poll, 1000); }
+function create() { me = $("name").value; call("POST", "/rooms", {name: me}).then(enter).catch(alert); }
+function join() { me = $("name").value; call("POST", "/rooms/" + $("code").value + "/join", {name: me}).then(enter).catch(alert); }
+function vote(a) { call("POST", "/rooms/" + code + "/vote", {name: me, answer: a}).then(show).catch(alert); }
+function leave() { call("POST", "/rooms/" + code + "/leave", {name: me}).then(function() { location.reload(); }).catch(alert); }
+function poll() { call("GET", "/rooms/" + code, {}).then(show).catch(function() {}); }
+function show(s) {
+	$("room").textContent = s.Code;
+	$("players").textContent = s.Players.join(", ");
+	var ul = $("messages"); ul.innerHTML = "";
+	(s.Messages || []).forEach(function(m) { var li = document.createElement("li"); li.textContent = m; ul.appendChild(li); });
+	$("image").hidden = !s.Image;
+	if (s.Image && $("image").src != s.Image) { $("image").src = s.Image; }
+	$("prompt").textContent = s.Over ? "Game over." : s.Prompt;
+	$("yesno").hidden = s.Over || !s.Prompt || !s.YesNo;
+	$("text").hidden = s.Over || !s.Prompt || s.YesNo;
+	$("votes").textContent = s.Prompt ? Object.keys(s.Votes).length + "/" + s.Players.length + " voted" : "";
+}
+</script>
+</body></html>
+`
@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+import (
+	"embed"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Curated starter databases
+//
+//go:embed seeds/*.json
+var seeds embed.FS
+
+// Names of embedded seeds
+func seedNames() []string {
+	entries, err := seeds.ReadDir("seeds")
+	if err != nil {
+		log.Panic("can not read seeds: ", err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".json"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Database initialized from named seed
+func seedDatabase(name string) (*database, error) {
+	content, err := seeds.ReadFile("seeds/" + name + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("unknown seed %q", name)
+	}
+	return parseDatabase(content)
+}
+
+func init() {
+	create := &command{
+		Name:  "create",
+		Args:  "database-file",
+		Short: "create new database, optionally from a starter set",
+		Run:   runCreate,
+	}
+	createSeed = create.Flag.String("seed", "", "starter set: "+strings.Join(seedNames(), ", "))
+	createCategory = create.Flag.String("category", defaultCategory, "kind of things to guess (without -seed)")
+	createForce = create.Flag.Bool("f", false, "overwrite existing file")
+	commands = append(commands, create)
+}
+
+var (
+	createSeed     *string
+	createCategory *string
+	createForce    *bool
+)
+
+func runCreate(cmd *command, args []string) {
+	if len(args) != 1 {
+		cmd.fail("database expected")
+	}
+	if _, err := os.Stat(args[0]); err == nil && !*createForce {
+		fmt.Fprintf(os.Stderr, "%s already exists (use -f to overwrite)\n", args[0])
+		os.Exit(1)
+	}
+	var d *database
+	if *createSeed != "" {
+		var err error
+		d, err = seedDatabase(*createSeed)
+		if err != nil {
+			cmd.fail("%s", err)
+		}
+	} else {
+		first := defaultRoot
+		p, ok := categories[*createCategory]
+		if !ok {
+			cmd.fail("unknown category %q: use -seed or play with -c -category", *createCategory)
+		}
+		first.Animal = p.first
+		d = newDatabase(&first)
+		if *createCategory != defaultCategory {
+			d.Category = *createCategory
+		}
+	}
+	err := d.save(args[0])
+	if err != nil {
+		log.Panic("can not save db: ", err)
+	}
+}
@@ -0,0 +1,238 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Branches of trees shared as short strings that players can paste in chat.
+// A share holds the questions leading to the branch and the branch itself,
+// compressed and base64-encoded.  Importing it teaches the animals of the
+// branch, answering questions of the receiving tree from the share when it
+// asks the same ones.
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+)
+
+func init() {
+	cmd := &command{
+		Name:  "share",
+		Args:  "database-file [animal]",
+		Short: "print animal or branch as a string to paste to import-share",
+		Run:   runShare,
+	}
+	sharePath = cmd.Flag.String("path", "", "answers leading to shared branch from root, e.g. yny")
+	commands = append(commands, cmd, &command{
+		Name:  "import-share",
+		Args:  "database-file share",
+		Short: "teach animals of string printed by share",
+		Run:   runImportShare,
+	})
+}
+
+var sharePath *string
+
+// Version prefix of shares
+const shareFormat = "aal1."
+
+// Largest decompressed share accepted
+const maxShareSize = 1 << 20
+
+type sharedBranch struct {
+	Category string `json:",omitempty"`
+	Path     []answeredQuestion
+	Tree     *node
+}
+
+// Copy of tree without statistics and annotations
+func bareTree(n *node) *node {
+	if n == nil {
+		return nil
+	}
+	return &node{Question: n.Question, Animal: n.Animal, No: bareTree(n.No), Yes: bareTree(n.Yes)}
+}
+
+func encodeShare(b *sharedBranch) (string, error) {
+	content, err := json.Marshal(b)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	w, _ := flate.NewWriter(&buf, flate.BestCompression)
+	w.Write(content)
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return shareFormat + base64.RawURLEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+func decodeShare(s string) (*sharedBranch, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, shareFormat) {
+		return nil, fmt.Errorf("not a share")
+	}
+	compressed, err := base64.RawURLEncoding.DecodeString(s[len(shareFormat):])
+	if err != nil {
+		return nil, err
+	}
+	content, err := ioutil.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(compressed)), maxShareSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > maxShareSize {
+		return nil, fmt.Errorf("share larger than %d bytes", maxShareSize)
+	}
+	b := new(sharedBranch)
+	if err := json.Unmarshal(content, b); err != nil {
+		return nil, err
+	}
+	if b.Tree == nil {
+		return nil, fmt.Errorf("empty share")
+	}
+	if problems := checkTree(b.Tree); len(problems) > 0 {
+		return nil, fmt.Errorf("%s", problems[0])
+	}
+	// Annotations are not shared.
+	b.Tree = bareTree(b.Tree)
+	return b, nil
+}
+
+// Answers to questions of share for its animals
+func (b *sharedBranch) attributes() *attributeTable {
+	t := &attributeTable{answers: make(map[string]map[string]bool)}
+	seen := make(map[string]bool)
+	addQuestion := func(q string) {
+		if !seen[q] {
+			seen[q] = true
+			t.questions = append(t.questions, q)
+		}
+	}
+	var walk func(n *node, answers map[string]bool)
+	walk = func(n *node, answers map[string]bool) {
+		if n.isLeaf() {
+			t.animals = append(t.animals, n.Animal)
+			t.answers[n.Animal] = answers
+			return
+		}
+		addQuestion(n.Question)
+		for _, yes := range []bool{false, true} {
+			a := make(map[string]bool)
+			for q, v := range answers {
+				a[q] = v
+			}
+			a[n.Question] = yes
+			child := n.No
+			if yes {
+				child = n.Yes
+			}
+			walk(child, a)
+		}
+	}
+	answers := make(map[string]bool)
+	for _, s := range b.Path {
+		addQuestion(s.Question)
+		answers[s.Question] = s.Yes
+	}
+	walk(b.Tree, answers)
+	return t
+}
+
+func runShare(cmd *command, args []string) {
+	if len(args) < 1 || len(args) > 2 {
+		cmd.fail("database and optional animal expected")
+	}
+	d, err := loadDatabase(args[0])
+	if err != nil {
+		log.Panic("can not load db: ", err)
+	}
+	var target *node
+	if len(args) == 2 {
+		target = mustFindAnimal(d, args[0], args[1])
+	} else if target, err = d.followPath(*sharePath); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", args[0], err)
+		os.Exit(1)
+	}
+	b := &sharedBranch{Category: d.Category, Path: []answeredQuestion{}, Tree: bareTree(target)}
+	for _, s := range pathTo(d.Root, target) {
+		b.Path = append(b.Path, answeredQuestion{s.question, s.yes})
+	}
+	s, err := encodeShare(b)
+	if err != nil {
+		log.Panic("can not encode share: ", err)
+	}
+	fmt.Println(s)
+}
+
+func runImportShare(cmd *command, args []string) {
+	if len(args) != 2 {
+		cmd.fail("database and share expected")
+	}
+	d, err := loadDatabase(args[0])
+	if err != nil {
+		log.Panic("can not load db: ", err)
+	}
+	b, err := decodeShare(args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "bad share: %s\n", err)
+		os.Exit(1)
+	}
+	if b.Category != d.Category {
+		fmt.Fprintf(os.Stderr, "%s: share is about %s, not %s\n", args[0], (&database{Category: b.Category}).category(), d.category())
+		os.Exit(1)
+	}
+	if err := moderateTree(b.Tree); err != nil {
+		fmt.Fprintf(os.Stderr, "bad share: %s\n", err)
+		os.Exit(1)
+	}
+
+	stdin = bufio.NewReader(os.Stdin)
+	attrs := b.attributes()
+	flags := make(map[string]string)
+	visit(b.Tree, func(n *node) {
+		if n.isLeaf() && n.Flagged != "" {
+			flags[n.Animal] = n.Flagged
+		}
+	})
+	for _, animal := range attrs.animals {
+		if !teachAnimal(d, animal, attrs) {
+			fmt.Printf(tr("%s: already known")+"\n", animal)
+			continue
+		}
+		fmt.Printf(tr("%s: learned")+"\n", animal)
+		if leaf := d.findAnimal(animal); leaf != nil && flags[animal] != "" {
+			leaf.Flagged = flags[animal]
+		}
+	}
+	err = d.save(args[0])
+	if err != nil {
+		log.Panic("can not save db: ", err)
+	}
+}
@@ -0,0 +1,76 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Variety in games.  When both answers to a question lead to the same
+// question, the two can be asked in either order: two answers reach the
+// same four branches whatever the order.  Games pick the order at random so
+// that repeat players do not always hear the same opening questions.
+
+import (
+	"flag"
+	"math/rand"
+	"time"
+)
+
+var seedFlag = flag.Int64("seed", 0, "seed making random choices reproducible (0: different every run)")
+
+var rng *rand.Rand
+
+func random() *rand.Rand {
+	if rng == nil {
+		seed := *seedFlag
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		rng = rand.New(rand.NewSource(seed))
+	}
+	return rng
+}
+
+// Whether both children of n ask the same question
+func twin(n *node) bool {
+	return !n.isLeaf() && !n.No.isLeaf() && !n.Yes.isLeaf() && sameName(n.No.Question, n.Yes.Question)
+}
+
+// Ask the question of the children of n, then the one of n, and record
+// answers where they belong in the tree.  Returns grandchild reached.
+func (g *game) askTwinFirst(n *node) *node {
+	twinQuestion := n.No.localized()
+	twinYes := g.ui.askYesNo(twinQuestion)
+	question := g.phrase(n)
+	yes := g.ui.askYesNo(question)
+	g.questions += 2
+	child := n.No
+	if yes {
+		child = n.Yes
+	}
+	g.path = append(g.path, step{twinQuestion, twinYes}, step{question, yes})
+	g.asked = append(g.asked, child, n)
+	child.recordAnswer(twinYes)
+	n.recordAnswer(yes)
+	if twinYes {
+		return child.Yes
+	}
+	return child.No
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Text-to-speech of terminal output
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"unicode"
+)
+
+var (
+	speakFlag    = flag.Bool("speak", false, "read prompts and messages aloud")
+	speakCommand = flag.String("speak-cmd", "", "command reading text aloud from stdin (default: say, espeak or Windows speech)")
+)
+
+// Command line of speech tool
+func speechCommand() []string {
+	if *speakCommand != "" {
+		return strings.Fields(*speakCommand)
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{"say"}
+	case "windows":
+		return []string{"powershell", "-NoProfile", "-Command",
+			"Add-Type -AssemblyName System.Speech; (New-Object System.Speech.Synthesis.SpeechSynthesizer).Speak([Console]::In.ReadToEnd())"}
+	}
+	argv := []string{"espeak", "--stdin"}
+	if lang := language(); lang != "" {
+		argv = append(argv, "-v", lang)
+	}
+	return argv
+}
+
+// Read text aloud if requested, waiting for the end so that several texts
+// do not overlap.  Speech is disabled after the first failure.
+func speak(text string) {
+	if !*speakFlag {
+		return
+	}
+	// Decorations such as emoji would be spelled out.
+	text = strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.So, r) {
+			return -1
+		}
+		return r
+	}, text)
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	argv := speechCommand()
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintln(os.Stderr, "can not speak, disabling speech:", err)
+		*speakFlag = false
+	}
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+import (
+	"embed"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Common names by category, one per line
+//
+//go:embed dictionary/*.txt
+var dictionaries embed.FS
+
+// Shortest name checked for typos
+const spellMinLength = 4
+
+// Number of typos tolerated in a name of the given length
+func maxTypos(length int) int {
+	if length >= 10 {
+		return 2
+	}
+	return 1
+}
+
+// Names players may have mistyped: leaves of d, of the seeds of the same
+// category and the common names of the category
+func (d *database) dictionary() []string {
+	var names []string
+	if content, err := dictionaries.ReadFile("dictionary/" + d.category() + ".txt"); err == nil {
+		names = strings.Split(strings.TrimSpace(string(content)), "\n")
+	}
+	for _, leaf := range leaves(d.Root) {
+		names = append(names, leaf.Animal, leaf.localized())
+	}
+	for _, s := range seedNames() {
+		seed, err := seedDatabase(s)
+		if err != nil || seed.category() != d.category() {
+			continue
+		}
+		for _, leaf := range leaves(seed.Root) {
+			names = append(names, leaf.Animal)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Known name closest to name if it looks like a typo
+func (d *database) suggest(name string) (string, bool) {
+	length := utf8.RuneCountInString(name)
+	if length < spellMinLength {
+		return "", false
+	}
+	best, bestDist := "", maxTypos(length)+1
+	for _, known := range d.dictionary() {
+		if sameName(known, name) {
+			return "", false
+		}
+		if dist := editDistance(fold(known), fold(name)); dist < bestDist {
+			best, bestDist = known, dist
+		}
+	}
+	return best, best != ""
+}
+
+// Ask player for a name, offering to fix typos
+func (g *game) askName(prompt string) string {
+	name := g.askContent(prompt, false)
+	if s, ok := g.db.suggest(name); ok && g.ui.askYesNo(fmt.Sprintf(tr("Did you mean %s?"), s)) {
+		return s
+	}
+	return name
+}
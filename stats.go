@@ -0,0 +1,269 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Play statistics stored on nodes.  They are local observations and are not
+// part of the op-log: merging keeps the statistics of the receiving copy.
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+func init() {
+	cmd := &command{
+		Name:  "stats",
+		Args:  "database-file",
+		Short: "print play statistics or tree metrics",
+		Run:   runStats,
+	}
+	statsTree = cmd.Flag.Bool("tree", false, "print shape of tree instead of play statistics")
+	statsCSV = cmd.Flag.Bool("csv", false, "print statistics of every question and animal as CSV")
+	commands = append(commands, cmd)
+}
+
+var (
+	statsTree *bool
+	statsCSV  *bool
+)
+
+// Number of animals listed by stats
+const statsTop = 10
+
+// Record that player chose animal of leaf n
+func (n *node) choose() {
+	n.ChosenCount++
+	n.LastChosen = time.Now().Unix()
+}
+
+func (n *node) recordAnswer(yes bool) {
+	if yes {
+		n.YesCount++
+	} else {
+		n.NoCount++
+	}
+}
+
+// Estimated probability that a player answers yes to question of n
+func (n *node) yesProbability() float64 {
+	// Laplace smoothing so that questions never answered count as even.
+	return float64(n.YesCount+1) / float64(n.YesCount+n.NoCount+2)
+}
+
+// Carry statistics, translations and other annotations of nodes of old
+// tree over to nodes of new one having the same ID.
+func copyStats(old *node, index map[string]*node) {
+	if old == nil {
+		return
+	}
+	if n := index[old.ID]; n != nil && n.isLeaf() == old.isLeaf() {
+		n.NoCount = old.NoCount
+		n.YesCount = old.YesCount
+		n.ChosenCount = old.ChosenCount
+		n.LastChosen = old.LastChosen
+		n.GuessCount = old.GuessCount
+		n.ConfusingCount = old.ConfusingCount
+		if n.Question == old.Question {
+			n.Variants = old.Variants
+		}
+		n.Translations = old.Translations
+		n.Guess = old.Guess
+		n.Flagged = old.Flagged
+		n.Description = old.Description
+		n.ImageURL = old.ImageURL
+	}
+	copyStats(old.No, index)
+	copyStats(old.Yes, index)
+}
+
+// Animal that may be guessed before reaching its leaf
+type candidate struct {
+	leaf *node
+
+	// Estimated probability of being the animal chosen by the player given
+	// that the game reached the subtree being explored
+	confidence float64
+}
+
+// Animals of subtree ranked by decreasing confidence and total number of
+// times they were chosen.  The estimate combines popularity of animals and
+// how players answered questions leading to them.
+func (n *node) candidates() (cs []candidate, choices int) {
+	var walk func(n *node, p float64)
+	walk = func(n *node, p float64) {
+		if n.isLeaf() {
+			cs = append(cs, candidate{n, p * float64(n.ChosenCount+1)})
+			choices += n.ChosenCount
+			return
+		}
+		yes := n.yesProbability()
+		walk(n.No, p*(1-yes))
+		walk(n.Yes, p*yes)
+	}
+	walk(n, 1)
+
+	var total float64
+	for _, c := range cs {
+		total += c.confidence
+	}
+	for i := range cs {
+		cs[i].confidence /= total
+	}
+	sort.SliceStable(cs, func(i, j int) bool { return cs[i].confidence > cs[j].confidence })
+	return
+}
+
+// Figures of subtree deciding whether to guess early, without ranking
+// all its animals, and showing progress
+type summary struct {
+	leaves  int
+	choices int
+	height  int // longest path to a leaf
+
+	// Sum of the estimates of candidates before normalization and
+	// estimate of the most likely one
+	weight float64
+	best   float64
+}
+
+// Confidence of best candidate of subtree
+func (s summary) confidence() float64 { return s.best / s.weight }
+
+// Summary of subtree n, computed along with the summaries of all its
+// subtrees the first time it is needed in the game.  Answers given since do
+// not change it, as they only affect the ancestors of the nodes explored
+// next.
+func (g *game) summarize(n *node) summary {
+	if s, ok := g.summaries[n]; ok {
+		return s
+	}
+	var s summary
+	if n.isLeaf() {
+		w := float64(n.ChosenCount + 1)
+		s = summary{1, n.ChosenCount, 0, w, w}
+	} else {
+		yes := n.yesProbability()
+		no, y := g.summarize(n.No), g.summarize(n.Yes)
+		s = summary{
+			leaves:  no.leaves + y.leaves,
+			choices: no.choices + y.choices,
+			height:  1 + max(no.height, y.height),
+			weight:  (1-yes)*no.weight + yes*y.weight,
+			best:    math.Max((1-yes)*no.best, yes*y.best),
+		}
+	}
+	g.summaries[n] = s
+	return s
+}
+
+func runStats(cmd *command, args []string) {
+	if len(args) != 1 {
+		cmd.fail("database expected")
+	}
+	d, err := loadDatabase(args[0])
+	if err != nil {
+		log.Panic("can not load db: ", err)
+	}
+	if *statsCSV {
+		writeStatsCSV(d.Root)
+		return
+	}
+	entries := listAnimals(d.Root, 0)
+	if *statsTree {
+		printTreeStats(entries)
+		return
+	}
+
+	games := 0
+	for _, e := range entries {
+		games += e.ChosenCount
+	}
+	fmt.Printf("games: %d\n", games)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].ChosenCount > entries[j].ChosenCount })
+	fmt.Println("most chosen animals:")
+	for i := 0; i < len(entries) && i < statsTop && entries[i].ChosenCount > 0; i++ {
+		fmt.Printf("    %s: %d\n", entries[i].Animal, entries[i].ChosenCount)
+	}
+}
+
+// Print size and depth of tree whose leaves are entries.  The balance factor
+// is the depth of a perfectly balanced tree with as many leaves divided by the
+// average depth: 1 for a balanced tree, towards 0 for a degenerate one.
+func printTreeStats(entries []animalEntry) {
+	minDepth, maxDepth, total := entries[0].Depth, 0, 0
+	for _, e := range entries {
+		if e.Depth < minDepth {
+			minDepth = e.Depth
+		}
+		if e.Depth > maxDepth {
+			maxDepth = e.Depth
+		}
+		total += e.Depth
+	}
+	avg := float64(total) / float64(len(entries))
+	balance := 1.0
+	if avg > 0 {
+		balance = math.Log2(float64(len(entries))) / avg
+	}
+
+	fmt.Printf("nodes: %d\n", 2*len(entries)-1)
+	fmt.Printf("leaves: %d\n", len(entries))
+	fmt.Printf("depth: min %d, avg %.2f, max %d\n", minDepth, avg, maxDepth)
+	fmt.Printf("balance: %.2f\n", balance)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Depth > entries[j].Depth })
+	fmt.Println("deepest animals:")
+	for i := 0; i < len(entries) && i < statsTop; i++ {
+		fmt.Printf("    %s: %d\n", entries[i].Animal, entries[i].Depth)
+	}
+}
+
+// Print one CSV record per node of tree: kind, question or animal, answers
+// leading to it, times the question was answered and how, times the animal
+// was proposed and chosen.  Cells not relevant to the kind of node are empty.
+func writeStatsCSV(root *node) {
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{"kind", "text", "path", "reached", "yes", "no", "guessed", "chosen"})
+	var walk func(n *node, path string)
+	walk = func(n *node, path string) {
+		if n.isLeaf() {
+			w.Write([]string{"animal", n.Animal, path, "", "", "",
+				strconv.Itoa(n.GuessCount), strconv.Itoa(n.ChosenCount)})
+			return
+		}
+		w.Write([]string{"question", n.Question, path, strconv.Itoa(n.YesCount + n.NoCount),
+			strconv.Itoa(n.YesCount), strconv.Itoa(n.NoCount), "", ""})
+		walk(n.No, path+"n")
+		walk(n.Yes, path+"y")
+	}
+	walk(root, "")
+	w.Flush()
+	if err := w.Error(); err != nil {
+		log.Panic("can not write statistics: ", err)
+	}
+}
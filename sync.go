@@ -0,0 +1,447 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Federation between instances: copies of a database exchange the ops they
+// are missing over HTTP.  Each side summarizes what it knows as a vector
+// clock mapping every replica to the highest op sequence number received
+// from it.  As a replica's ops are always propagated in full past what the
+// receiver knows, this is enough to compute the missing ops.
+//
+// Endpoints served by "ask-and-learn serve":
+//
+//	GET  /ops?base=B&since=VC  ops not covered by vector clock VC (JSON)
+//	POST /ops?base=B           merge ops in request body (JSON array)
+//	GET  /clock?base=B         vector clock of the served database
+//	POST /contributions        animal taught elsewhere (with -collect, see telemetry.go)
+//	GET  /admin                curator dashboard (see admin.go)
+//	GET  /graphql              GraphQL API (see graphql.go)
+//
+// B is the fingerprint of the base tree: instances not sharing the same base
+// can not merge and answer 409 Conflict.
+//
+// Anybody may push animals learned but deleting, renaming and rephrasing
+// require the secret shared by trusted peers, held by the variable named in
+// the PeerTokenEnv setting and sent as a bearer token.  Pushed texts are
+// moderated like taught ones.
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Highest op sequence number known per replica
+type vectorClock map[string]uint64
+
+func (d *database) vectorClock() vectorClock {
+	vc := make(vectorClock)
+	for _, o := range d.Ops {
+		if o.Seq > vc[o.Replica] {
+			vc[o.Replica] = o.Seq
+		}
+	}
+	return vc
+}
+
+// Ops of d not covered by vc
+func (d *database) opsSince(vc vectorClock) []*op {
+	ops := []*op{}
+	for _, o := range d.Ops {
+		if o.Seq > vc[o.Replica] {
+			ops = append(ops, o)
+		}
+	}
+	return ops
+}
+
+// Fingerprint of the base tree
+func (d *database) baseID() string {
+	content, err := json.Marshal(d.Base)
+	if err != nil {
+		log.Panic("can not marshal db: ", err)
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Database shared between HTTP handlers.  The lock serializes writers of
+// db, readers use the snapshot (see cow.go).
+type server struct {
+	sync.Mutex
+	db   *database
+	path string
+
+	snapshot atomic.Value // *database
+	freezer  freezer
+
+	// Changes made from the dashboard (see admin.go)
+	history history
+}
+
+func newServer(d *database, path string) *server {
+	s := &server{db: d, path: path}
+	s.publish()
+	return s
+}
+
+// Save and publish working database.  Must be called with lock held.
+func (s *server) save() {
+	s.publish()
+	err := s.db.save(s.path)
+	if err != nil {
+		log.Print("can not save db: ", err)
+	}
+}
+
+func (s *server) checkBase(w http.ResponseWriter, r *http.Request) bool {
+	if b := r.FormValue("base"); b != s.view().baseID() {
+		http.Error(w, "database does not derive from the same base", http.StatusConflict)
+		return false
+	}
+	return true
+}
+
+// Largest POST /ops body accepted from peers not sharing the secret
+const maxAnonymousOps = 1 << 20
+
+func (s *server) handleOps(w http.ResponseWriter, r *http.Request) {
+	if !s.checkBase(w, r) {
+		return
+	}
+	switch r.Method {
+	case "GET":
+		vc := make(vectorClock)
+		if since := r.FormValue("since"); since != "" {
+			if err := json.Unmarshal([]byte(since), &vc); err != nil {
+				http.Error(w, "bad vector clock: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		writeJSON(w, s.view().opsSince(vc))
+	case "POST":
+		trusted := isTrustedPeer(r)
+		body := r.Body
+		if !trusted {
+			body = http.MaxBytesReader(w, r.Body, maxAnonymousOps)
+		}
+		var ops []*op
+		if err := json.NewDecoder(body).Decode(&ops); err != nil {
+			http.Error(w, "bad ops: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !trusted {
+			for _, o := range ops {
+				if o.Kind != opLearn {
+					http.Error(w, "only trusted peers can send "+o.Kind+" ops", http.StatusForbidden)
+					return
+				}
+			}
+		}
+		if kept := moderateOps(ops); len(kept) < len(ops) {
+			log.Printf("%s: %d op(s) rejected by moderation", r.RemoteAddr, len(ops)-len(kept))
+			ops = kept
+		}
+		vc, err := s.mergeOps(ops, r.RemoteAddr)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		writeJSON(w, vc)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// Merge ops received from peer and return the resulting vector clock
+func (s *server) mergeOps(ops []*op, peer string) (vectorClock, error) {
+	s.Lock()
+	defer s.Unlock()
+	if err := s.db.checkIncoming(ops); err != nil {
+		return nil, err
+	}
+	if n := s.db.merge(ops); n > 0 {
+		log.Printf("%s: %d new change(s)", peer, n)
+		s.save()
+	}
+	return s.db.vectorClock(), nil
+}
+
+func (s *server) handleClock(w http.ResponseWriter, r *http.Request) {
+	if !s.checkBase(w, r) {
+		return
+	}
+	writeJSON(w, s.view().vectorClock())
+}
+
+// Secret shared by trusted peers, "" if there is none
+func peerToken() string {
+	if env := settings().PeerTokenEnv; env != "" {
+		return os.Getenv(env)
+	}
+	return ""
+}
+
+// Report whether request carries the secret shared by trusted peers
+func isTrustedPeer(r *http.Request) bool {
+	token := peerToken()
+	return token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) == 1
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Print("can not send reply: ", err)
+	}
+}
+
+// Client side of the protocol
+type peer struct {
+	url    string // root URL of remote instance
+	client http.Client
+}
+
+func newPeer(rawurl string) *peer {
+	return &peer{url: strings.TrimRight(rawurl, "/"), client: http.Client{Timeout: 30 * time.Second}}
+}
+
+func (p *peer) call(method, endpoint string, query url.Values, in, out interface{}) error {
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, p.url+endpoint+"?"+query.Encode(), &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token := peerToken(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Exchange missing ops with p in both directions.  Returns number of ops
+// pulled and pushed.  The database is only accessed through with, which
+// runs its argument on it, e.g. under a lock not to be held while talking
+// to p.
+func (p *peer) sync(with func(f func(d *database))) (pulled, pushed int, err error) {
+	var base string
+	var since []byte
+	with(func(d *database) {
+		base = d.baseID()
+		since, err = json.Marshal(d.vectorClock())
+	})
+	if err != nil {
+		return
+	}
+	var ops []*op
+	err = p.call("GET", "/ops", url.Values{"base": {base}, "since": {string(since)}}, nil, &ops)
+	if err != nil {
+		return
+	}
+	ops = moderateOps(ops)
+	with(func(d *database) { pulled = d.merge(ops) })
+
+	var remote vectorClock
+	err = p.call("GET", "/clock", url.Values{"base": {base}}, nil, &remote)
+	if err != nil {
+		return
+	}
+	var missing []*op
+	with(func(d *database) { missing = d.opsSince(remote) })
+	if len(missing) > 0 {
+		err = p.call("POST", "/ops", url.Values{"base": {base}}, missing, &remote)
+		if err != nil {
+			return
+		}
+		pushed = len(missing)
+	}
+	return
+}
+
+// Number of attempts and delay before first retry when uploading database
+const (
+	uploadAttempts = 5
+	uploadBackoff  = time.Second
+)
+
+// POST content of database file to url, retrying with exponential backoff on
+// network and server errors.
+func upload(rawurl string, path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	client := http.Client{Timeout: 30 * time.Second}
+	delay := uploadBackoff
+	for attempt := 1; ; attempt++ {
+		var resp *http.Response
+		resp, err = client.Post(rawurl, "application/json", bytes.NewReader(content))
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode/100 == 2 {
+				return nil
+			}
+			err = fmt.Errorf("%s", resp.Status)
+			if resp.StatusCode/100 == 4 {
+				// Client errors are not going to improve
+				return err
+			}
+		}
+		if attempt == uploadAttempts {
+			return err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// Repeat flag
+type stringList []string
+
+func (l *stringList) String() string     { return strings.Join(*l, ",") }
+func (l *stringList) Set(v string) error { *l = append(*l, v); return nil }
+
+func init() {
+	serve := &command{
+		Name:  "serve",
+		Args:  "database-file",
+		Short: "serve database to other instances and to players over HTTP",
+		Run:   runServe,
+	}
+	serveAddr = serve.Flag.String("addr", ":8080", "listen address")
+	serve.Flag.Var(&servePeers, "peer", "URL of instance to sync with periodically (repeatable)")
+	servePeriod = serve.Flag.Duration("interval", 5*time.Minute, "delay between syncs with peers")
+	serveCollect = serve.Flag.String("collect", "", "file to append animals contributed by players to")
+	commands = append(commands, serve, &command{
+		Name:  "sync",
+		Args:  "database-file peer-url...",
+		Short: "exchange learned animals with remote instances",
+		Run:   runSync,
+	})
+}
+
+var (
+	serveAddr    *string
+	servePeers   stringList
+	servePeriod  *time.Duration
+	serveCollect *string
+)
+
+func runServe(cmd *command, args []string) {
+	if len(args) != 1 {
+		cmd.fail("database expected")
+	}
+	d, err := loadDatabase(args[0])
+	if err != nil {
+		log.Panic("can not load db: ", err)
+	}
+	s := newServer(d, args[0])
+	if len(servePeers) > 0 {
+		go s.syncPeriodically(servePeers, *servePeriod)
+	}
+	http.HandleFunc("/ops", s.handleOps)
+	http.HandleFunc("/clock", s.handleClock)
+	s.handleRooms()
+	s.handleAdmin()
+	http.HandleFunc("/graphql", s.handleGraphQL)
+	http.HandleFunc("/graphql/schema", handleGraphQLSchema)
+	if *serveCollect != "" {
+		http.HandleFunc("/contributions", s.handleContribution)
+	}
+	log.Printf("serving %s on %s", args[0], *serveAddr)
+	log.Fatal(http.ListenAndServe(*serveAddr, nil))
+}
+
+func (s *server) syncPeriodically(urls []string, period time.Duration) {
+	for {
+		for _, u := range urls {
+			pulled, pushed, err := newPeer(u).sync(func(f func(d *database)) {
+				s.Lock()
+				defer s.Unlock()
+				f(s.db)
+			})
+			if pulled > 0 {
+				s.Lock()
+				s.save()
+				s.Unlock()
+			}
+			if err != nil {
+				log.Printf("%s: can not sync: %s", u, err)
+			} else if pulled+pushed > 0 {
+				log.Printf("%s: pulled %d, pushed %d", u, pulled, pushed)
+			}
+		}
+		time.Sleep(period)
+	}
+}
+
+func runSync(cmd *command, args []string) {
+	if len(args) < 2 {
+		cmd.fail("database and peer expected")
+	}
+	d, err := loadDatabase(args[0])
+	if err != nil {
+		log.Panic("can not load db: ", err)
+	}
+	failed := false
+	for _, u := range args[1:] {
+		pulled, pushed, err := newPeer(u).sync(func(f func(d *database)) { f(d) })
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: can not sync: %s\n", u, err)
+			failed = true
+		}
+		fmt.Printf("%s: pulled %d, pushed %d\n", u, pulled, pushed)
+	}
+	err = d.save(args[0])
+	if err != nil {
+		log.Panic("can not save db: ", err)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
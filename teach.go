@@ -0,0 +1,153 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+func init() {
+	cmd := &command{
+		Name:  "import-animals",
+		Args:  "database-file list-file",
+		Short: "teach animals listed in a file, one per line, optionally followed by an image URL",
+		Run:   runImportAnimals,
+	}
+	importAttributes = cmd.Flag.String("attributes", "", "CSV file of answers used to place animals without asking")
+	commands = append(commands, cmd)
+}
+
+var importAttributes *string
+
+func runImportAnimals(cmd *command, args []string) {
+	if len(args) != 2 {
+		cmd.fail("database and list expected")
+	}
+	d, err := loadDatabase(args[0])
+	if err != nil {
+		log.Panic("can not load db: ", err)
+	}
+	var attrs *attributeTable
+	if *importAttributes != "" {
+		attrs, err = loadAttributes(*importAttributes)
+		if err != nil {
+			log.Panic("can not load attributes: ", err)
+		}
+	}
+	names, err := readList(args[1])
+	if err != nil {
+		log.Panic("can not read list: ", err)
+	}
+
+	stdin = bufio.NewReader(os.Stdin)
+	for _, line := range names {
+		name, image := splitImageURL(line)
+		v, why := moderate(name, false)
+		if v == reject {
+			fmt.Printf("%s: %s\n", name, why)
+			continue
+		}
+		if v == flagged && why == "" {
+			why = tr("flagged")
+		}
+		if teachAnimal(d, name, attrs) {
+			fmt.Printf(tr("%s: learned")+"\n", name)
+		} else {
+			fmt.Printf(tr("%s: already known")+"\n", name)
+		}
+		if leaf := d.findAnimal(name); leaf != nil {
+			if image != "" {
+				leaf.ImageURL = image
+			}
+			if v == flagged {
+				leaf.Flagged = why
+			}
+		}
+		// Save as we go so that interrupting a long import loses nothing.
+		err = d.save(args[0])
+		if err != nil {
+			log.Panic("can not save db: ", err)
+		}
+	}
+}
+
+// Non-empty lines of file, ignoring #-comments
+func readList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var names []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			names = append(names, line)
+		}
+	}
+	return names, scanner.Err()
+}
+
+// Insert animal in tree.  Questions are answered from attrs when possible,
+// by the user otherwise.  Returns false if animal was already known, wherever
+// it is in the tree.
+func teachAnimal(d *database, animal string, attrs *attributeTable) bool {
+	if d.findAnimal(animal) != nil {
+		return false
+	}
+	n := d.Root
+	for !n.isLeaf() {
+		yes, ok := attrs.answer(animal, n.Question)
+		if !ok {
+			yes = askYesNo("[%s] %s", animal, n.Question)
+		}
+		if yes {
+			n = n.Yes
+		} else {
+			n = n.No
+		}
+	}
+	question, isYesLeaf, ok := attrs.distinguish(animal, n.Animal)
+	var why string
+	if ok {
+		var v verdict
+		if v, why = moderate(question, true); v == reject {
+			ok = false
+		} else if v == flagged && why == "" {
+			why = tr("flagged")
+		}
+	}
+	if !ok {
+		p := d.phrasing()
+		question, why = askModerated(p.distinguish, d.named(animal), d.named(n.Animal))
+		isYesLeaf = askYesNo(p.expected, d.named(animal))
+	}
+	d.learn(n, &node{Animal: animal}, question, isYesLeaf)
+	n.Flagged = why
+	return true
+}
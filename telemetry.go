@@ -0,0 +1,178 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Contributions of animals taught to a community server so that maintainers
+// can grow the official seed databases.  Nothing is sent unless the player
+// configures the server URL (see config.go), and contributions carry no
+// player name or replica identifier.  "ask-and-learn serve -collect FILE"
+// accepts them on POST /contributions and appends them to FILE as JSON
+// lines, but only as many per client address and hour as contributionRate,
+// only those moderation neither rejects nor flags and only until FILE
+// reaches maxCollectSize.
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	contributionRate = 30       // per client address and hour
+	maxCollectSize   = 16 << 20 // bytes
+)
+
+// Animal taught and question distinguishing it, answered yes or not
+type contribution struct {
+	Category string
+	Language string
+	Animal   string
+	Question string
+	Yes      bool
+}
+
+// Send animal just taught to community server, in the background like hooks
+func (g *game) contribute(animal, question string, yes bool) {
+	url := settings().TelemetryURL
+	if url == "" {
+		return
+	}
+	lang := language()
+	if lang == "" {
+		lang = g.db.language()
+	}
+	body, _ := json.Marshal(contribution{g.db.category(), lang, animal, question, yes})
+	queueHook(func() {
+		client := http.Client{Timeout: hookTimeout}
+		resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "can not reach community server:", err)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			fmt.Fprintln(os.Stderr, "community server refused contribution:", resp.Status)
+		}
+	})
+}
+
+// Times of recent contributions by client address
+type rateLimiter struct {
+	sync.Mutex
+	max  int
+	per  time.Duration
+	seen map[string][]time.Time
+}
+
+var contributions = rateLimiter{max: contributionRate, per: time.Hour}
+
+// Record request from client and return whether it is within the limit
+func (l *rateLimiter) allow(client string) bool {
+	l.Lock()
+	defer l.Unlock()
+	now := time.Now()
+	for c, times := range l.seen {
+		for len(times) > 0 && now.Sub(times[0]) > l.per {
+			times = times[1:]
+		}
+		if len(times) == 0 {
+			delete(l.seen, c)
+		} else {
+			l.seen[c] = times
+		}
+	}
+	if len(l.seen[client]) >= l.max {
+		return false
+	}
+	if l.seen == nil {
+		l.seen = make(map[string][]time.Time)
+	}
+	l.seen[client] = append(l.seen[client], now)
+	return true
+}
+
+func (s *server) handleContribution(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !contributions.allow(host) {
+		http.Error(w, "too many contributions", http.StatusTooManyRequests)
+		return
+	}
+	var c contribution
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<12)).Decode(&c); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.Animal = strings.TrimSpace(c.Animal)
+	c.Question = strings.TrimSpace(c.Question)
+	if c.Animal == "" || c.Question == "" {
+		http.Error(w, "animal and question expected", http.StatusBadRequest)
+		return
+	}
+	if v, _ := moderate(c.Animal, false); v != allow {
+		http.Error(w, "animal refused by moderation", http.StatusUnprocessableEntity)
+		return
+	}
+	if v, _ := moderate(c.Question, true); v != allow {
+		http.Error(w, "question refused by moderation", http.StatusUnprocessableEntity)
+		return
+	}
+	line, _ := json.Marshal(c)
+
+	s.Lock()
+	defer s.Unlock()
+	f, err := os.OpenFile(*serveCollect, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err == nil {
+		var fi os.FileInfo
+		if fi, err = f.Stat(); err == nil && fi.Size()+int64(len(line)) >= maxCollectSize {
+			f.Close()
+			http.Error(w, "no room for more contributions", http.StatusInsufficientStorage)
+			return
+		}
+	}
+	if err == nil {
+		_, err = f.Write(append(line, '\n'))
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		log.Print("can not record contribution: ", err)
+		http.Error(w, "can not record contribution", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,182 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Standard input, or the speech recognizer (see listen.go), is read by a
+// background goroutine so that questions can be given a time limit.
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"time"
+)
+
+var (
+	answerTimeout = flag.Duration("timeout", 0, "time allowed to answer each question (0: unlimited)")
+	timeoutAnswer = flag.String("timeout-answer", "", "answer assumed when time is up: yes or no (default: forfeit game)")
+)
+
+// Raised in game when player runs out of time
+var errForfeit = errors.New("forfeit")
+
+type inputLine struct {
+	text string
+	err  error
+	at   time.Time // when read
+}
+
+var lines chan inputLine
+
+// Whether time was up before the last line expected arrived, and lines read
+// before the next one is expected, which answer the question whose time is
+// up rather than the next one
+var (
+	timedOut   bool
+	lateBefore time.Time
+)
+
+// Next input line not answering a question whose time is up
+func nextLine(timeout <-chan time.Time) (l inputLine, ok, expired bool) {
+	if timedOut {
+		timedOut = false
+		lateBefore = time.Now()
+	}
+	for {
+		select {
+		case l, ok = <-lines:
+			if ok && l.err == nil && l.at.Before(lateBefore) {
+				continue
+			}
+			return l, ok, false
+		case <-timeout:
+			timedOut = true
+			return l, false, true
+		}
+	}
+}
+
+// Start reading stdin in background if not done yet
+func startInput() {
+	if lines != nil {
+		return
+	}
+	lines = make(chan inputLine)
+	if *listenCommand != "" {
+		startListening()
+		return
+	}
+	go readStdin()
+}
+
+// Feed lines of stdin as input lines
+func readStdin() {
+	for {
+		s, err := stdin.ReadString('\n')
+		if s != "" {
+			// Last line may lack its newline.
+			lines <- inputLine{s, nil, time.Now()}
+		}
+		if err != nil {
+			if err != io.EOF {
+				lines <- inputLine{"", err, time.Now()}
+			}
+			close(lines)
+			return
+		}
+	}
+}
+
+// Next line of input, including newline
+func readLine() (string, error) {
+	startInput()
+	listen()
+	l, ok, _ := nextLine(nil)
+	if !ok {
+		return "", io.EOF
+	}
+	return l.text, l.err
+}
+
+// Next line of input or false if none arrives before timeout
+func readLineBefore(timeout time.Duration) (string, bool, error) {
+	startInput()
+	listen()
+	l, ok, expired := nextLine(time.After(timeout))
+	switch {
+	case expired:
+		cancelListening()
+		return "", false, nil
+	case !ok:
+		return "", true, io.EOF
+	}
+	return l.text, true, l.err
+}
+
+// Ask yes-or-no question within time limit if any
+func askYesNoTimed(kind promptKind, prompt string) bool {
+	if *answerTimeout <= 0 {
+		return askYesNoAs(kind, prompt)
+	}
+	speak(prompt)
+	deadline := time.Now().Add(*answerTimeout)
+	for {
+		left := time.Until(deadline)
+		if left <= 0 {
+			break
+		}
+		if piped() {
+			showPrompt(output().prompt(kind, prompt))
+		} else {
+			showPrompt(fmt.Sprintf("%s [%ds]", output().prompt(kind, prompt), int(left.Seconds()+0.5)))
+		}
+		s, ok, err := readLineBefore(left)
+		if !ok {
+			break
+		}
+		if err == io.EOF {
+			endOfInput()
+		}
+		if err != nil {
+			log.Panic("error when reading stdin:", err)
+		}
+		s = compose(trimLine(s))
+		echoAnswer(s)
+		checkQuit(s)
+		if kind != yesNoPrompt {
+			checkPause(s)
+		}
+		if yes, valid := parseYesNo(s); valid {
+			return yes
+		}
+		rejectAnswer(tr("Please answer yes or no."))
+	}
+	fmt.Fprintln(out)
+	if *timeoutAnswer != "" {
+		fmt.Fprintf(out, tr("Time is up, assuming %s.")+"\n", tr(*timeoutAnswer))
+		return *timeoutAnswer == "yes"
+	}
+	panic(errForfeit)
+}
@@ -0,0 +1,127 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+)
+
+// Language of databases not specifying one
+const defaultLanguage = "en"
+
+func (d *database) language() string {
+	if d.Language == "" {
+		return defaultLanguage
+	}
+	return d.Language
+}
+
+// Question or animal
+func (n *node) text() string {
+	if n.isLeaf() {
+		return n.Animal
+	}
+	return n.Question
+}
+
+// Text of node in selected language, falling back to the database language
+func (n *node) localized() string {
+	if t, ok := n.Translations[language()]; ok {
+		return t
+	}
+	return n.text()
+}
+
+// Record that text of n, just taught, is in the selected language if it is
+// not the database one
+func (d *database) noteLanguage(n *node) {
+	lang := language()
+	if lang == "" || lang == d.language() {
+		return
+	}
+	if n.Translations == nil {
+		n.Translations = make(map[string]string)
+	}
+	n.Translations[lang] = n.text()
+}
+
+func init() {
+	cmd := &command{
+		Name:  "translate",
+		Args:  "database-file",
+		Short: "translate questions and animals lacking a translation",
+		Run:   runTranslate,
+	}
+	translateLang = cmd.Flag.String("lang", "", "target language, e.g. fr")
+	commands = append(commands, cmd)
+}
+
+var translateLang *string
+
+func runTranslate(cmd *command, args []string) {
+	if len(args) != 1 || *translateLang == "" {
+		cmd.fail("database and language expected")
+	}
+	d, err := loadDatabase(args[0])
+	if err != nil {
+		log.Panic("can not load db: ", err)
+	}
+	if *translateLang == d.language() {
+		cmd.fail("database already in %s", *translateLang)
+	}
+	stdin = bufio.NewReader(os.Stdin)
+	fmt.Println(`Type translations, "-" to skip, "." to stop.`)
+	var nodes []*node
+	var walk func(n *node)
+	walk = func(n *node) {
+		if _, ok := n.Translations[*translateLang]; !ok {
+			nodes = append(nodes, n)
+		}
+		if !n.isLeaf() {
+			walk(n.No)
+			walk(n.Yes)
+		}
+	}
+	walk(d.Root)
+
+	for _, n := range nodes {
+		t := ask("%s:", n.text())
+		if t == "." {
+			break
+		}
+		if t == "-" {
+			continue
+		}
+		if n.Translations == nil {
+			n.Translations = make(map[string]string)
+		}
+		n.Translations[*translateLang] = t
+		err = d.save(args[0])
+		if err != nil {
+			log.Panic("can not save db: ", err)
+		}
+	}
+}
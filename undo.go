@@ -0,0 +1,320 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Undo and redo of curation changes made with the editor (see edit.go) and
+// the dashboard (see admin.go).  Changes are undone by recording
+// compensating ops, e.g. renaming an animal back or teaching a deleted
+// animal again, so that whatever happened to the database since, games
+// played or ops merged, is kept and undoing propagates to other copies like
+// any change.  Changes that rebase the database can not be undone.
+//
+// The editor also journals its working database after each change so that
+// an interrupted session can be recovered.
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// Maximum number of changes that can be undone
+const undoDepth = 100
+
+// Change that can be undone and redone
+type change interface {
+	undo(d *database) error
+	redo(d *database) error
+}
+
+var errGone = errors.New("node changed since, can not undo or redo")
+
+// Undo and redo stacks of changes
+type history struct {
+	undo, redo []change
+}
+
+// Record change just made
+func (h *history) push(c change) {
+	h.undo = append(h.undo, c)
+	if len(h.undo) > undoDepth {
+		h.undo = h.undo[1:]
+	}
+	h.redo = nil
+}
+
+// Forget changes, e.g. after a rebase
+func (h *history) clear() {
+	h.undo, h.redo = nil, nil
+}
+
+var errNothing = errors.New("nothing to undo or redo")
+
+// Undo last change of d
+func (h *history) back(d *database) error {
+	if len(h.undo) == 0 {
+		return errNothing
+	}
+	c := h.undo[len(h.undo)-1]
+	if err := c.undo(d); err != nil {
+		return err
+	}
+	h.undo = h.undo[:len(h.undo)-1]
+	h.redo = append(h.redo, c)
+	return nil
+}
+
+// Redo last undone change of d
+func (h *history) forward(d *database) error {
+	if len(h.redo) == 0 {
+		return errNothing
+	}
+	c := h.redo[len(h.redo)-1]
+	if err := c.redo(d); err != nil {
+		return err
+	}
+	h.redo = h.redo[:len(h.redo)-1]
+	h.undo = append(h.undo, c)
+	return nil
+}
+
+func (d *database) nodeByID(id string) *node {
+	var found *node
+	visit(d.Root, func(n *node) {
+		if n.ID == id {
+			found = n
+		}
+	})
+	return found
+}
+
+// Renaming of animal or rephrasing of question
+type renaming struct {
+	id, from, to string
+}
+
+func (c *renaming) set(d *database, text string) error {
+	n := d.nodeByID(c.id)
+	if n == nil {
+		return errGone
+	}
+	if n.isLeaf() {
+		d.rename(n, n.Animal, text)
+	} else {
+		d.editQuestion(n, text)
+	}
+	return nil
+}
+
+func (c *renaming) undo(d *database) error { return c.set(d, c.from) }
+func (c *renaming) redo(d *database) error { return c.set(d, c.to) }
+
+// Removal of animal, undone by teaching it again next to its former sibling
+type removal struct {
+	leafID, siblingID string
+	isYes             bool // whether leaf was the yes child
+
+	// Former content of leaf and of its parent question, children aside
+	leaf, parent node
+}
+
+// Removal of leaf about to be removed, nil if leaf is the root and can not
+// be removed
+func newRemoval(d *database, leaf *node) *removal {
+	parent := parentOf(d.Root, leaf)
+	if parent == nil {
+		return nil
+	}
+	c := &removal{leafID: leaf.ID, isYes: parent.Yes == leaf, leaf: *leaf, parent: *parent}
+	if parent.Yes == leaf {
+		c.siblingID = parent.No.ID
+	} else {
+		c.siblingID = parent.Yes.ID
+	}
+	c.parent.No, c.parent.Yes = nil, nil
+	return c
+}
+
+func (c *removal) undo(d *database) error {
+	sibling := d.nodeByID(c.siblingID)
+	if sibling == nil || d.findAnimal(c.leaf.Animal) != nil {
+		return errGone
+	}
+	leaf := c.leaf
+	leaf.ID = ""
+	d.learn(sibling, &leaf, c.parent.Question, c.isYes)
+	c.leafID = leaf.ID
+	// sibling is now the question node: restore its statistics.
+	q := sibling
+	q.NoCount, q.YesCount, q.ConfusingCount = c.parent.NoCount, c.parent.YesCount, c.parent.ConfusingCount
+	q.Variants, q.Translations, q.Guess, q.Flagged = c.parent.Variants, c.parent.Translations, c.parent.Guess, c.parent.Flagged
+	return nil
+}
+
+func (c *removal) redo(d *database) error {
+	leaf := d.nodeByID(c.leafID)
+	if leaf == nil || !leaf.isLeaf() || !d.remove(leaf) {
+		return errGone
+	}
+	return nil
+}
+
+// Change undoing another, e.g. teaching an animal
+type inverse struct {
+	change
+}
+
+func (c inverse) undo(d *database) error { return c.change.redo(d) }
+func (c inverse) redo(d *database) error { return c.change.undo(d) }
+
+// Changes made at once, undone in reverse order
+type changes []change
+
+func (cs changes) undo(d *database) error {
+	for i := len(cs) - 1; i >= 0; i-- {
+		if err := cs[i].undo(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cs changes) redo(d *database) error {
+	for _, c := range cs {
+		if err := c.redo(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Approval of node awaiting review (see admin.go)
+type approval struct {
+	id, flagged string
+	confusing   int
+}
+
+func (c *approval) undo(d *database) error {
+	n := d.nodeByID(c.id)
+	if n == nil {
+		return errGone
+	}
+	n.Flagged, n.ConfusingCount = c.flagged, c.confusing
+	return nil
+}
+
+func (c *approval) redo(d *database) error {
+	n := d.nodeByID(c.id)
+	if n == nil {
+		return errGone
+	}
+	n.Flagged, n.ConfusingCount = "", 0
+	return nil
+}
+
+// File journaling the working database of the editor
+func (e *editor) journalPath() string {
+	return e.path + ".journal"
+}
+
+func (e *editor) writeJournal() {
+	if err := e.d.save(e.journalPath()); err != nil {
+		log.Panic("can not write journal: ", err)
+	}
+}
+
+func (e *editor) removeJournal() {
+	if err := os.Remove(e.journalPath()); err != nil && !os.IsNotExist(err) {
+		log.Panic("can not remove journal: ", err)
+	}
+}
+
+// Offer to resume changes journaled by an interrupted session
+func (e *editor) recoverJournal() {
+	info, err := os.Stat(e.journalPath())
+	if err != nil {
+		return
+	}
+	prompt := fmt.Sprintf("Recover unsaved changes of session interrupted %s?", info.ModTime().Format(time.Stamp))
+	if !askYesNo("%s", prompt) {
+		e.removeJournal()
+		return
+	}
+	d, err := loadDatabase(e.journalPath())
+	if err != nil {
+		log.Panic("can not load journal: ", err)
+	}
+	e.d = d
+	e.nodes = []*node{d.Root}
+	e.dirty = true
+}
+
+// Record change made by the command being run
+func (e *editor) changed(c change) {
+	e.dirty = true
+	e.history.push(c)
+	e.writeJournal()
+}
+
+// Ask confirmation of a change that rebases the database, which copies of
+// it can then no longer merge with
+func (e *editor) confirmRebase() bool {
+	return askYesNo("%s", "Other copies of the database will no longer merge with it and changes made so far can not be undone. Continue?")
+}
+
+// Record change that rebased the database
+func (e *editor) rebased() {
+	e.dirty = true
+	e.history.clear()
+	e.writeJournal()
+}
+
+// Go as far as answers lead from the root
+func (e *editor) follow(answers string) {
+	e.nodes = []*node{e.d.Root}
+	for _, a := range answers {
+		n := e.current()
+		if n.isLeaf() {
+			break
+		}
+		if a == 'y' {
+			e.nodes = append(e.nodes, n.Yes)
+		} else {
+			e.nodes = append(e.nodes, n.No)
+		}
+	}
+}
+
+// Undo or redo change with f
+func (e *editor) undo(f func(d *database) error) {
+	answers := e.answers()
+	if err := f(e.d); err != nil {
+		fmt.Println(err)
+		return
+	}
+	e.follow(answers)
+	e.dirty = true
+	e.writeJournal()
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2011 Nicolas Thery (nthery@gmail.com)
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package main
+
+// Optional lookup of animals about to be taught on Wikipedia to check that
+// they exist and remember a one-line description shown when they are
+// guessed and a picture.
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+var wikipediaFlag = flag.Bool("wikipedia", false, "look up taught animals on Wikipedia")
+
+// Wikipedia server, %s being the language
+const wikipediaURL = "https://%s.wikipedia.org/api/rest_v1/page/summary/"
+
+// Summary and image URL of Wikipedia page about name, ok false if there is
+// none
+func wikipediaSummary(lang, name string) (summary, image string, ok bool, err error) {
+	title := strings.ReplaceAll(strings.TrimSpace(name), " ", "_")
+	req, err := http.NewRequest("GET", fmt.Sprintf(wikipediaURL, lang)+url.PathEscape(title), nil)
+	if err != nil {
+		return "", "", false, err
+	}
+	req.Header.Set("User-Agent", "ask-and-learn")
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", "", false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", "", false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", "", false, fmt.Errorf("%s", resp.Status)
+	}
+	var page struct {
+		Type, Description, Extract string
+		Thumbnail                  struct{ Source string }
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return "", "", false, err
+	}
+	if page.Type == "disambiguation" {
+		return "", "", true, nil
+	}
+	return firstSentence(page.Extract, page.Description), page.Thumbnail.Source, true, nil
+}
+
+// First sentence of text or fallback if text is empty
+func firstSentence(text, fallback string) string {
+	if i := strings.Index(text, ". "); i >= 0 {
+		text = text[:i+1]
+	}
+	if text == "" {
+		return fallback
+	}
+	return text
+}
+
+// Check that animal about to be taught exists on Wikipedia, letting the
+// player correct its name if not.  Returns the name and the summary and
+// picture of its page if any.
+func (g *game) lookUp(animal string) (name, summary, image string) {
+	lang := language()
+	if lang == "" {
+		lang = g.db.language()
+	}
+	for {
+		summary, image, ok, err := wikipediaSummary(lang, animal)
+		switch {
+		case err != nil:
+			fmt.Fprintln(os.Stderr, "can not query Wikipedia:", err)
+			return animal, "", ""
+		case ok:
+			return animal, summary, image
+		}
+		if g.ui.askYesNo(fmt.Sprintf(tr("I could not find %s on Wikipedia. Is it spelled right?"), animal)) {
+			return animal, "", ""
+		}
+		animal = g.askName(tr("How is it spelled?"))
+	}
+}